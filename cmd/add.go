@@ -1,44 +1,222 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
+	"github.com/carloscastrojumo/remindme/pkg/logger"
 	prompt "github.com/carloscastrojumo/remindme/pkg/prompt"
+	"github.com/carloscastrojumo/remindme/pkg/runner"
+	"github.com/carloscastrojumo/remindme/pkg/secrets"
 	"github.com/carloscastrojumo/remindme/pkg/storage"
 	"github.com/spf13/cobra"
 )
 
 var note storage.Note
 
+var onSecret string
+
+var commandFile string
+
+var useEditor bool
+
+var inputFormat string
+
+var onDuplicate string
+
 func init() {
-	addCmd.Flags().StringArrayVar(&note.Tags, "tags", []string{}, "Tags to add to the note")
+	addCmd.Flags().StringArrayVarP(&note.Tags, "tags", "t", []string{}, "Tags to add to the note")
 	addCmd.Flags().StringVar(&note.Command, "command", "", "Command to add to the note")
 	addCmd.Flags().StringVar(&note.Description, "description", "", "Description to add to the note")
+	addCmd.Flags().BoolVar(&note.Secret, "secret", false, "Encrypt the command, it's hidden in listings unless --reveal is given")
+	addCmd.Flags().StringVar(&commandFile, "command-file", "", "Read the command from a file, - for the standard input")
+	addCmd.Flags().BoolVar(&useEditor, "editor", false, "Write the command in $VISUAL or $EDITOR, e.g. a multi-line script")
+	addCmd.Flags().StringVar(&note.Shell, "shell", "", "Shell or language running the command, e.g. bash or python (defaults to your shell)")
+	addCmd.Flags().StringVar(&inputFormat, "format", "", "Format of the notes piped to rmm add: command, json, ndjson or yaml (detected by default)")
+	addCmd.Flags().StringVar(&onSecret, "on-secret", "", "What to do when the command looks like it holds a secret: prompt, abort, redact, secret or allow")
+	addCmd.Flags().StringVar(&onDuplicate, "on-duplicate", "", "What to do when a note has the same command: prompt, merge, skip or add")
 	rootCmd.AddCommand(addCmd)
 }
 
 var addCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add new note to the database",
-	Long:  `Add new note to the database`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// if the user didn't provide any flags, we prompt for the note
-		if note.Command == "" && note.Description == "" && len(note.Tags) == 0 {
-			note = promptNote()
+	Long: `Add new note to the database.
+
+Multi-line commands, e.g. scripts with heredocs or shell functions, can be written with --editor
+or read with --command-file, from a file or the standard input (-). --shell sets what runs them.
+
+Notes can be piped to rmm add: a single command, e.g. 'history | tail -1 | rmm add -t git', or many notes
+as a JSON array (e.g. from 'rmm list -o json'), JSON lines or a YAML list (e.g. a YAML data file), each with
+a command and optionally a description, tags, shell and secret. Notes that aren't valid are reported by line
+and the others are added, e.g. secret notes listed without --reveal, their command is masked. --tags are added to every note.
+
+Commands are checked for likely secrets: AWS keys, tokens, passwords, private keys and random looking strings.
+When one is found --on-secret decides what to do:
+  prompt  ask (the default in a terminal)
+  abort   don't add the note (the default otherwise)
+  redact  replace the secrets by placeholders, e.g. <PASSWORD>
+  secret  add the note as secret, its command is encrypted
+  allow   add the note as it is
+
+When a note already has the same command --on-duplicate decides what to do:
+  prompt  ask (the default in a terminal)
+  merge   merge the note into it: the tags are added, its description is kept unless it has none
+  skip    don't add the note
+  add     add it anyway, with a warning (the default otherwise, existing notes are only changed when asked to)
+Commands that only differ in whitespace, quoting or the order of their flags are reported as near-duplicates,
+they're only merged when chosen at the prompt. Use 'rmm dedupe' to merge the duplicates already added.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if note.Command == "" && commandFile == "" && !useEditor && !prompt.IsInteractive() {
+			return addFromInput(cmd, os.Stdin)
+		}
+
+		if commandFile != "" {
+			if note.Command != "" {
+				return usageError("--command and --command-file can't be used together")
+			}
+			command, err := readCommandFile(commandFile)
+			if err != nil {
+				return err
+			}
+			note.Command = command
+		}
+
+		switch {
+		case useEditor:
+			// the editor starts with the command given, if any
+			command, err := prompt.ForText(note.Command, runner.Extension(note.Shell))
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(command) == "" {
+				return errors.New("the command is empty, note not added")
+			}
+			note.Command = command
+			if note.Description == "" && len(note.Tags) == 0 {
+				promptNote(&note)
+			}
+		case note.Command == "" && note.Description == "" && len(note.Tags) == 0:
+			// if the user didn't provide any flags, we prompt for the note
+			promptNote(&note)
+		}
+
+		if err := checkSecrets(&note); err != nil {
+			return err
+		}
+
+		notes, err := noteService.Notes(cmd.Context())
+		if err != nil {
+			return err
+		}
+		i, err := resolveDuplicate(note, notes, 0)
+		if errors.Is(err, errSkipped) {
+			logger.Warn("Note not added")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if i >= 0 {
+			if err := noteService.Update(cmd.Context(), storage.MergeNotes(notes[i], note)); err != nil {
+				return err
+			}
+			logger.Success("Note merged into note %s", notes[i].ID)
+			return nil
 		}
 
-		if err := noteService.Add(note); err != nil {
-			panic(err)
+		// duplicates were resolved above
+		if _, err := noteService.CreateAnyway(cmd.Context(), note); err != nil {
+			return err
 		}
 
-		fmt.Println("Note added successfully")
+		logger.Success("Note added successfully")
+		return nil
 	},
 }
 
-func promptNote() storage.Note {
-	note := storage.Note{}
-	note.Command = prompt.ForString("Command")
+// checkSecrets looks for secrets in the command of a note about to be added
+// and redacts them, marks the note secret or aborts as chosen with --on-secret
+func checkSecrets(note *storage.Note) error {
+	if note.Secret {
+		return nil
+	}
+	findings := secrets.Scan(note.Command)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	logger.Warn("The command looks like it holds secrets:")
+	for _, f := range findings {
+		logger.Warn("    %s: %s", f.Kind, f.Masked())
+	}
+
+	action := onSecret
+	if action == "" {
+		action = "abort"
+		if prompt.IsInteractive() {
+			action = "prompt"
+		}
+	}
+
+	if action == "prompt" {
+		actions := []string{"abort", "redact", "secret", "allow"}
+		choice, err := prompt.ForChoice("What do you want to do?", []string{
+			"Abort",
+			"Redact them: " + secrets.Redact(note.Command, findings),
+			"Add the note as secret, its command is encrypted",
+			"Add the note as it is",
+		})
+		if err != nil {
+			return err
+		}
+		action = actions[choice]
+	}
+
+	switch action {
+	case "abort":
+		return errors.New("note not added, use --on-secret to redact the secrets, add the note as secret or allow it")
+	case "redact":
+		note.Command = secrets.Redact(note.Command, findings)
+		logger.Warn("Secrets redacted: %s", note.Command)
+	case "secret":
+		note.Secret = true
+	case "allow":
+	default:
+		return usageError("unknown secret action %q (prompt, abort, redact, secret, allow)", action)
+	}
+	return nil
+}
+
+// promptNote prompts for the fields of the note not given yet
+func promptNote(note *storage.Note) {
+	if note.Command == "" {
+		note.Command = prompt.ForString("Command")
+	}
 	note.Description = prompt.ForString("Description")
 	note.Tags = prompt.ForStringArray("Tags")
-	return note
+}
+
+// readCommandFile reads a command from a file, or the standard input for -, without the final newline
+func readCommandFile(name string) (string, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read the command: %w", err)
+	}
+
+	command := strings.TrimRight(string(data), "\r\n")
+	if strings.TrimSpace(command) == "" {
+		return "", fmt.Errorf("no command in %s", name)
+	}
+	return command, nil
 }
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/duplicate"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/prompt"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+)
+
+// errSkipped is returned when a duplicate note isn't added
+var errSkipped = errors.New("note not added, a note has the same command")
+
+// resolveDuplicate looks for notes with the same command, or nearly, as a note about to be added.
+// It returns the position of the note to merge it into as chosen with --on-duplicate, -1 to add it.
+// Near-duplicates are only merged when chosen at the prompt. line prefixes the messages when it's set.
+func resolveDuplicate(note storage.Note, notes []storage.Note, line int) (int, error) {
+	duplicates := storage.FindDuplicates(notes, note.Command)
+	if len(duplicates) == 0 {
+		return -1, nil
+	}
+
+	prefix := ""
+	if line > 0 {
+		prefix = fmt.Sprintf("line %d: ", line)
+	}
+	exact := duplicates[0].Kind == duplicate.Exact
+	if exact {
+		logger.Warn("%sthe command is already in note %s", prefix, duplicates[0].Note.ID)
+	} else {
+		logger.Warn("%sthe command is nearly the same as:", prefix)
+		for _, d := range duplicates {
+			command := d.Note.Command
+			if d.Note.Secret {
+				command = "(secret)"
+			}
+			logger.Warn("    note %s, %s: %s", d.Note.ID, d.Kind, command)
+		}
+	}
+
+	action := duplicateAction()
+	if action == "prompt" {
+		if !prompt.IsInteractive() {
+			return -1, usageError("can't prompt for duplicates when not running in a terminal, use --on-duplicate")
+		}
+
+		items := []string{}
+		for _, d := range duplicates {
+			items = append(items, fmt.Sprintf("Merge it into note %s, its tags are added", d.Note.ID))
+		}
+		items = append(items, "Add it anyway", "Don't add it")
+		choice, err := prompt.ForChoice("What do you want to do?", items)
+		if err != nil {
+			return -1, err
+		}
+
+		switch choice {
+		case len(duplicates):
+			return -1, nil
+		case len(duplicates) + 1:
+			return -1, errSkipped
+		}
+		return position(notes, duplicates[choice].Note.ID), nil
+	}
+
+	// near-duplicates may be different commands, they're only merged when chosen at the prompt
+	if !exact {
+		return -1, nil
+	}
+
+	switch action {
+	case "merge":
+		return position(notes, duplicates[0].Note.ID), nil
+	case "skip":
+		return -1, errSkipped
+	case "add":
+		if onDuplicate == "" {
+			logger.Warn("%sadding it anyway, use --on-duplicate merge or skip to change the note or leave it out", prefix)
+		}
+		return -1, nil
+	}
+	return -1, usageError("unknown duplicate action %q (prompt, merge, skip, add)", action)
+}
+
+// duplicateAction returns what to do with duplicates: --on-duplicate, else prompt in a terminal
+// and add otherwise, existing notes are only changed when asked to
+func duplicateAction() string {
+	switch {
+	case onDuplicate != "":
+		return onDuplicate
+	case prompt.IsInteractive():
+		return "prompt"
+	}
+	return "add"
+}
+
+// position returns the position of the note with the ID
+func position(notes []storage.Note, id string) int {
+	for i, note := range notes {
+		if note.ID == id {
+			return i
+		}
+	}
+	return -1
+}
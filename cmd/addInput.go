@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/carloscastrojumo/remindme/pkg/bulk"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+// addFromInput adds the notes piped to rmm add. Notes that aren't valid or hold secrets are
+// reported with their line and left out, the others are added and a summary is printed.
+func addFromInput(cmd *cobra.Command, input io.Reader) error {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return fmt.Errorf("could not read the notes: %w", err)
+	}
+	if len(data) == 0 {
+		return errors.New("nothing to add, give a --command or pipe notes to rmm add")
+	}
+
+	records, err := bulk.Parse(data, inputFormat)
+	if err != nil {
+		return err
+	}
+
+	existing, err := noteService.Notes(cmd.Context())
+	if err != nil {
+		return err
+	}
+	// merged are the existing notes other notes were merged into, by position
+	merged := make(map[int]storage.Note)
+	// byCommand are the positions of the notes to add by command, duplicates in the input are merged too
+	byCommand := make(map[string]int)
+
+	lines := []int{}
+	notes := []storage.Note{}
+	failed, skipped, mergedCount := 0, 0, 0
+	for _, record := range records {
+		if record.Err != nil {
+			logger.Error("line %d: %s", record.Line, record.Err)
+			failed++
+			continue
+		}
+
+		n := storage.Note{
+			Command:     record.Note.Command,
+			Description: record.Note.Description,
+			Tags:        appendMissing(record.Note.Tags, note.Tags),
+			Shell:       record.Note.Shell,
+			Secret:      record.Note.Secret || note.Secret,
+		}
+		if n.Description == "" {
+			n.Description = note.Description
+		}
+		if n.Shell == "" {
+			n.Shell = note.Shell
+		}
+
+		if err := checkSecrets(&n); err != nil {
+			logger.Error("line %d: %s", record.Line, err)
+			failed++
+			continue
+		}
+
+		i, err := resolveDuplicate(n, existing, record.Line)
+		switch {
+		case errors.Is(err, errSkipped):
+			skipped++
+			continue
+		case err != nil:
+			return err
+		case i >= 0:
+			if _, ok := merged[i]; !ok {
+				merged[i] = existing[i]
+			}
+			existing[i] = storage.MergeNotes(existing[i], n)
+			mergedCount++
+			continue
+		}
+
+		if j, ok := byCommand[n.Command]; ok {
+			switch duplicateAction() {
+			case "add":
+				if onDuplicate == "" {
+					logger.Warn("line %d: the command is already on line %d, adding it anyway", record.Line, lines[j])
+				}
+			case "skip":
+				skipped++
+				continue
+			default:
+				notes[j] = storage.MergeNotes(notes[j], n)
+				mergedCount++
+				continue
+			}
+		}
+		byCommand[n.Command] = len(notes)
+		lines = append(lines, record.Line)
+		notes = append(notes, n)
+	}
+
+	added := 0
+	for i, err := range noteService.AddAll(cmd.Context(), notes) {
+		if err != nil {
+			logger.Error("line %d: %s", lines[i], err)
+			failed++
+			continue
+		}
+		added++
+	}
+
+	for i, before := range merged {
+		// e.g. notes listed and added back
+		if sameNote(before, existing[i]) {
+			continue
+		}
+		if err := noteService.Update(cmd.Context(), existing[i]); err != nil {
+			logger.Error("note %s: %s", existing[i].ID, err)
+			failed++
+		}
+	}
+
+	if len(records) == 1 && added == 1 {
+		logger.Success("Note added successfully")
+		return nil
+	}
+
+	logger.Success("%d of %d notes added", added, len(records))
+	if mergedCount > 0 || skipped > 0 {
+		logger.Warn("%d duplicates merged into other notes, %d skipped", mergedCount, skipped)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d notes were not added", failed)
+	}
+	return nil
+}
+
+// sameNote reports whether merging notes into a note didn't change it
+func sameNote(a storage.Note, b storage.Note) bool {
+	return a.Description == b.Description && a.Shell == b.Shell && a.Secret == b.Secret &&
+		a.Uses == b.Uses && slices.Equal(a.Tags, b.Tags)
+}
+
+// appendMissing appends the values not in values yet
+func appendMissing(values []string, others []string) []string {
+	result := append([]string{}, values...)
+	for _, other := range others {
+		found := false
+		for _, value := range result {
+			if value == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, other)
+		}
+	}
+	return result
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manage the backups of the notes",
+	Long: `Manage the backups of the notes. The YAML storage takes a backup of the data file
+before every change, see yaml.backups and yaml.backupMaxAge in the configuration.`,
+	Annotations: map[string]string{noStorage: ""},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+}
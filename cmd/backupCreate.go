@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Take a backup of the notes",
+	Long:  "Take a backup of the notes as they are now",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backup, err := noteService.CreateBackup()
+		if err != nil {
+			return err
+		}
+		logger.Success("Backup %s created", backup.Name)
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupCreateCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var backupDiffCmd = &cobra.Command{
+	Use:   "diff [backup]",
+	Short: "Show the notes added, removed or changed since a backup",
+	Long: `Show the notes added (+), removed (-) or changed (~) since a backup was taken.
+The backup can be given by the start of its name, e.g. 20261019-1530.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		diff, err := noteService.DiffBackup(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		if diff.Empty() {
+			logger.Info("No changes since backup %s", args[0])
+			return nil
+		}
+		printDiff(diff, false)
+		return nil
+	},
+}
+
+// printDiff prints the changes between two versions of the notes, reversed shows them
+// from the newer version to the older one, e.g. what a restore would do
+func printDiff(diff *storage.Diff, reversed bool) {
+	added, removed := diff.Added, diff.Removed
+	if reversed {
+		added, removed = removed, added
+	}
+
+	for _, note := range added {
+		color.Green("+ %s %s", note.ID, shownCommand(note))
+	}
+	for _, note := range removed {
+		color.Red("- %s %s", note.ID, shownCommand(note))
+	}
+	for _, change := range diff.Changed {
+		before, after := change.Before, change.After
+		if reversed {
+			before, after = after, before
+		}
+
+		color.Yellow("~ %s %s", after.ID, shownCommand(after))
+		if before.Command != after.Command {
+			color.Yellow("    command: %s -> %s", shownCommand(before), shownCommand(after))
+		}
+		if before.Description != after.Description {
+			color.Yellow("    description: %s -> %s", before.Description, after.Description)
+		}
+		if strings.Join(before.Tags, ",") != strings.Join(after.Tags, ",") {
+			color.Yellow("    tags: [%s] -> [%s]", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", "))
+		}
+	}
+}
+
+// shownCommand returns the command of a note as it's shown in a diff, secret commands are masked
+func shownCommand(note storage.Note) string {
+	if note.Secret {
+		return "******** (secret)"
+	}
+	return note.Command
+}
+
+func init() {
+	backupCmd.AddCommand(backupDiffCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var backupListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the backups, oldest first",
+	Long:    "List the backups, oldest first. Use the backup name to restore or diff it",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backups, err := noteService.Backups()
+		if err != nil {
+			return err
+		}
+
+		if len(backups) == 0 {
+			logger.Info("No backups found")
+			return nil
+		}
+
+		color.Yellow("----- Backups -----")
+		for _, backup := range backups {
+			color.Green("%s  %s  %d bytes", backup.Name, backup.Time.Local().Format("2006-01-02 15:04:05"), backup.Size)
+		}
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupListCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var restoreConfirmed bool
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore [backup]",
+	Short: "Replace the notes with a backup",
+	Long: `Replace the notes with the ones in a backup. The backup can be given by the start of its name,
+e.g. 20261019-1530. The current notes are backed up first, so a restore can be undone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		diff, err := noteService.DiffBackup(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		if diff.Empty() {
+			logger.Info("The notes are the same as in backup %s", args[0])
+			return nil
+		}
+
+		if !restoreConfirmed {
+			if !prompt.IsInteractive() {
+				return usageError("use --yes to restore a backup when not running in a terminal")
+			}
+			printDiff(diff, true)
+			if !prompt.Confirm("Restore backup " + args[0]) {
+				return nil
+			}
+		}
+
+		if err := noteService.RestoreBackup(args[0]); err != nil {
+			return err
+		}
+		logger.Success("Backup %s restored", args[0])
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupRestoreCmd)
+	backupRestoreCmd.Flags().BoolVarP(&restoreConfirmed, "yes", "y", false, "Restore without asking for confirmation")
+}
@@ -6,11 +6,12 @@ import (
 )
 
 var configCmd = &cobra.Command{
-	Use:   "config",
-	Short: "Prints the current configuration file to screen",
-	Long:  `Prints the current configuration file to screen`,
-	Run: func(cmd *cobra.Command, args []string) {
-		config.GetConfig()
+	Use:         "config",
+	Short:       "Prints the current configuration file to screen",
+	Long:        `Prints the current configuration file to screen`,
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return config.GetConfig(profileName)
 	},
 }
 
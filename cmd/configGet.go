@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configGetCmd = &cobra.Command{
+	Use:         "get [key]",
+	Short:       "Print a configuration value",
+	Long:        "Print a configuration value, e.g. 'rmm config get yaml.name', environment overrides are taken into account",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value := config.Get(args[0])
+		if value == nil {
+			return fmt.Errorf("key %q %w", args[0], errdefs.ErrNotFound)
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}, []interface{}:
+			data, err := yaml.Marshal(v)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(data))
+		default:
+			fmt.Println(v)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var initProfile config.Profile
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the configuration file",
+	Long: `Create the configuration file with the given storage configuration.
+If --storage is not given the configuration is prompted, which requires a terminal.`,
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+
+		if initProfile.StorageType == "" {
+			if !prompt.IsInteractive() {
+				return usageError("--storage is required when not running in a terminal")
+			}
+			initProfile = config.PromptProfile()
+		}
+
+		if err := config.Init(initProfile, force); err != nil {
+			return err
+		}
+		logger.Success("Configuration file created")
+		return nil
+	},
+}
+
+func init() {
+	configInitCmd.Flags().StringVar(&initProfile.StorageType, "storage", "", "Storage type (mongo, yaml, http)")
+	configInitCmd.Flags().StringVar(&initProfile.Yaml.Name, "yaml-file", "", "YAML data file (defaults to ~/.config/remindme/data.yaml)")
+	configInitCmd.Flags().StringVar(&initProfile.Mongo.Host, "mongo-host", "localhost", "Mongo host")
+	configInitCmd.Flags().IntVar(&initProfile.Mongo.Port, "mongo-port", 27017, "Mongo port")
+	configInitCmd.Flags().StringVar(&initProfile.Mongo.Database, "mongo-database", "notes", "Mongo database")
+	configInitCmd.Flags().StringVar(&initProfile.Mongo.Collection, "mongo-collection", "notes", "Mongo collection")
+	configInitCmd.Flags().StringVar(&initProfile.Mongo.URI, "mongo-uri", "", "Mongo connection string (mongodb:// or mongodb+srv://), replaces host and port")
+	configInitCmd.Flags().StringVar(&initProfile.Mongo.Username, "mongo-username", "", "Mongo username")
+	configInitCmd.Flags().StringVar(&initProfile.Mongo.PasswordEnv, "mongo-password-env", "", "Environment variable holding the Mongo password")
+	configInitCmd.Flags().StringVar(&initProfile.Mongo.PasswordFile, "mongo-password-file", "", "File holding the Mongo password")
+	configInitCmd.Flags().BoolVar(&initProfile.Mongo.TLS, "mongo-tls", false, "Connect to Mongo using TLS")
+	configInitCmd.Flags().StringVar(&initProfile.Mongo.TLSCAFile, "mongo-tls-ca-file", "", "CA certificates used to verify the Mongo server")
+	configInitCmd.Flags().StringVar(&initProfile.HTTP.URL, "http-url", "", "URL of the rmm serve server, e.g. http://notes.internal:8080")
+	configInitCmd.Flags().StringVar(&initProfile.HTTP.TokenEnv, "http-token-env", "", "Environment variable holding the server token")
+	configInitCmd.Flags().StringVar(&initProfile.HTTP.TokenFile, "http-token-file", "", "File holding the server token")
+	configInitCmd.Flags().Bool("force", false, "Replace an existing storage configuration")
+	configCmd.AddCommand(configInitCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var keygenForce bool
+
+var configKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate the encryption key",
+	Long: `Generate a random encryption key in the key file (encryption.keyFile, ~/.config/remindme/key by default),
+or in the OS keyring when encryption.keyring is set.
+The key encrypts secret notes and the YAML data files with yaml.encrypt set. It can be given in $RMM_KEY instead.
+Keep a copy of the key, encrypted notes can't be read without it.`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		location, err := config.GenerateKey(keygenForce)
+		if err != nil {
+			return err
+		}
+		logger.Success("Encryption key written to %s", location)
+		return nil
+	},
+}
+
+func init() {
+	configKeygenCmd.Flags().BoolVar(&keygenForce, "force", false, "Replace the existing key")
+	configCmd.AddCommand(configKeygenCmd)
+}
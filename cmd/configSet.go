@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var configSetCmd = &cobra.Command{
+	Use:         "set [key] [value]",
+	Short:       "Set a configuration value",
+	Long:        "Set a configuration value in the configuration file, e.g. 'rmm config set mongo.port 27018'",
+	Args:        cobra.ExactArgs(2),
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Set(args[0], args[1]); err != nil {
+			return err
+		}
+		logger.Success("%s set", args[0])
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+}
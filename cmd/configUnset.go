@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var configUnsetCmd = &cobra.Command{
+	Use:         "unset [key]",
+	Short:       "Remove a configuration value",
+	Long:        "Remove a configuration value from the configuration file, e.g. 'rmm config unset currentProfile'",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Unset(args[0]); err != nil {
+			return err
+		}
+		logger.Success("%s removed", args[0])
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configUnsetCmd)
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:         "db",
+	Short:       "Manage the storage database",
+	Long:        `Manage the storage database`,
+	Annotations: map[string]string{noStorage: ""},
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Prepare the storage, e.g. create the Mongo search indexes",
+	Long: `Prepare the storage, e.g. create the Mongo text index on command, description and tags
+and the index on tags. It's safe to run it any number of times.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrated, err := noteService.Migrate(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if !migrated {
+			logger.Info("Nothing to migrate for this storage")
+			return nil
+		}
+		logger.Success("Storage migrated")
+		return nil
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(dbMigrateCmd)
+}
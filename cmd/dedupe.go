@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/duplicate"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/prompt"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var dedupeConfirmed bool
+
+var dedupeDryRun bool
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find and merge duplicate notes",
+	Long: `Find the notes with the same command, or nearly: commands that only differ in whitespace,
+quoting or the order of their flags. For each group of duplicates you choose the note to keep,
+the others are merged into it: their tags are added, its description is kept unless it has none
+and their uses are added up.
+
+--yes merges the notes with exactly the same command into the most used one without asking,
+other near-duplicates are only merged when chosen in a terminal. --dry-run only lists the duplicates.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		notes, err := noteService.Notes(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		groups := storage.FindDuplicateGroups(notes)
+		if len(groups) == 0 {
+			logger.Info("No duplicates found")
+			return nil
+		}
+
+		if !dedupeDryRun && !dedupeConfirmed && !prompt.IsInteractive() {
+			return usageError("use --yes to merge the duplicates when not running in a terminal, or --dry-run to list them")
+		}
+
+		merged, removed := 0, 0
+		for _, group := range groups {
+			color.Yellow("%d notes, %s:", len(group.Notes), group.Kind)
+			for _, note := range group.Notes {
+				printDuplicate(note)
+			}
+
+			keep, err := chooseKept(group)
+			if err != nil {
+				return err
+			}
+
+			sets := [][]storage.Note{}
+			switch {
+			case keep >= 0:
+				sets = append(sets, keepFirst(group.Notes, keep))
+			case dedupeConfirmed && !dedupeDryRun && !prompt.IsInteractive():
+				// notes of near-duplicates with exactly the same command are still merged
+				for _, same := range sameCommands(group.Notes) {
+					sets = append(sets, keepFirst(same, mostUsed(same)))
+				}
+			}
+
+			for _, set := range sets {
+				if err := noteService.Merge(cmd.Context(), set); err != nil {
+					return err
+				}
+				logger.Success("Notes merged into note %s", set[0].ID)
+				removed += len(set) - 1
+			}
+			if len(sets) > 0 {
+				merged++
+			}
+			fmt.Println()
+		}
+
+		if dedupeDryRun {
+			logger.Info("%d groups of duplicates found", len(groups))
+			return nil
+		}
+		logger.Success("%d of %d groups merged, %d notes removed", merged, len(groups), removed)
+		return nil
+	},
+}
+
+// chooseKept returns the position of the note of the group the others are merged into, -1 to skip the group
+func chooseKept(group storage.DuplicateGroup) (int, error) {
+	switch {
+	case dedupeDryRun:
+		return -1, nil
+	case dedupeConfirmed && group.Kind == duplicate.Exact:
+		return mostUsed(group.Notes), nil
+	case !prompt.IsInteractive():
+		logger.Warn("Near-duplicates are only merged when chosen in a terminal")
+		return -1, nil
+	}
+
+	items := []string{}
+	for _, note := range group.Notes {
+		items = append(items, fmt.Sprintf("Keep note %s and merge the others into it", note.ID))
+	}
+	items = append(items, "Skip", "Stop")
+	choice, err := prompt.ForChoice("Which note do you want to keep?", items)
+	if err != nil {
+		return -1, err
+	}
+
+	switch choice {
+	case len(group.Notes):
+		return -1, nil
+	case len(group.Notes) + 1:
+		return -1, errors.New("stopped, the duplicates left weren't merged")
+	}
+	return choice, nil
+}
+
+// keepFirst moves the note kept first, the others are merged into it
+func keepFirst(notes []storage.Note, keep int) []storage.Note {
+	ordered := append([]storage.Note{notes[keep]}, notes[:keep]...)
+	return append(ordered, notes[keep+1:]...)
+}
+
+// sameCommands returns the notes with exactly the same command as another one, by command
+func sameCommands(notes []storage.Note) [][]storage.Note {
+	commands := []string{}
+	byCommand := make(map[string][]storage.Note)
+	for _, note := range notes {
+		if _, ok := byCommand[note.Command]; !ok {
+			commands = append(commands, note.Command)
+		}
+		byCommand[note.Command] = append(byCommand[note.Command], note)
+	}
+
+	sets := [][]storage.Note{}
+	for _, command := range commands {
+		if len(byCommand[command]) > 1 {
+			sets = append(sets, byCommand[command])
+		}
+	}
+	return sets
+}
+
+// mostUsed returns the position of the most used note, the first one when they're used as much
+func mostUsed(notes []storage.Note) int {
+	most := 0
+	for i, note := range notes {
+		if note.Uses > notes[most].Uses {
+			most = i
+		}
+	}
+	return most
+}
+
+// printDuplicate prints a note of a group of duplicates on a line
+func printDuplicate(note storage.Note) {
+	command := note.Command
+	if note.Secret {
+		command = "(secret)"
+	} else if first, _, multiline := strings.Cut(command, "\n"); multiline {
+		command = first + " ..."
+	}
+	color.HiBlue("    %s %s %s %s %s", color.WhiteString(note.ID), color.RedString("%s", command),
+		color.GreenString("[%s]", strings.Join(note.Tags, ", ")), color.WhiteString("%s", note.Description),
+		color.HiBlackString("(%d uses)", note.Uses))
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+	dedupeCmd.Flags().BoolVarP(&dedupeConfirmed, "yes", "y", false, "Merge the notes with the same command without asking")
+	dedupeCmd.Flags().BoolVar(&dedupeDryRun, "dry-run", false, "Only list the duplicates")
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/doctor"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration and the storage",
+	Long: `Check the configuration file, the data file or database of every profile,
+duplicate notes and the clipboard tools, printing how to fix each problem found.
+Exits with a non-zero status when a problem is found.`,
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles := config.ProfileNames()
+		if cmd.Flags().Changed("profile") {
+			profiles = selectedProfiles(cmd)
+		}
+
+		problems := 0
+		for _, result := range doctor.Run(cmd.Context(), profiles) {
+			if result.OK() {
+				color.Green("✔ %s", result.Name)
+				continue
+			}
+
+			problems++
+			color.Red("✘ %s: %s", result.Name, result.Problem)
+			if result.Fix != "" {
+				color.Yellow("  fix: %s", result.Fix)
+			}
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("%d problem(s) found", problems)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of rmm, rmm run exits with the exit status of the command it runs
+const (
+	exitOK = 0
+	// exitError is any error without a code of its own
+	exitError = 1
+	// exitUsage is an invalid command, argument or flag
+	exitUsage = 2
+	// exitNotFound is a note, backup, profile or key that doesn't exist
+	exitNotFound = 3
+	// exitAmbiguous is an ID, or the start of one, matching several notes or backups
+	exitAmbiguous = 4
+	// exitUnavailable is a storage that can't be reached or read, e.g. MongoDB is down
+	exitUnavailable = 5
+	// exitKey is a missing or wrong encryption key
+	exitKey = 6
+	// exitConflict is a merge or sync conflict, or a note added with the command of another one
+	exitConflict = 7
+	// exitInterrupted is an interruption with Ctrl-C
+	exitInterrupted = 130
+)
+
+// exitCodes map errors to their exit code, the first one matching is used
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{context.Canceled, exitInterrupted},
+	{errdefs.ErrUsage, exitUsage},
+	{errdefs.ErrNotFound, exitNotFound},
+	{errdefs.ErrAmbiguousID, exitAmbiguous},
+	{errdefs.ErrConflict, exitConflict},
+	{errdefs.ErrDuplicate, exitConflict},
+	{crypt.ErrNoKey, exitKey},
+	{crypt.ErrWrongKey, exitKey},
+	{errdefs.ErrStorageUnavailable, exitUnavailable},
+}
+
+// exitCode returns the exit code of an error
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return exitError
+}
+
+// usageError returns an error for an invalid argument or flag
+func usageError(format string, a ...interface{}) error {
+	return errdefs.Mark(fmt.Errorf(format, a...), errdefs.ErrUsage)
+}
+
+// markUsageErrors marks the errors of the argument checks of the command and its subcommands
+// as usage errors, flag errors are marked by the root command
+func markUsageErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			return errdefs.Mark(validate(cmd, args), errdefs.ErrUsage)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
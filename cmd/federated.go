@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+// sourcesErr is the error of the profiles that couldn't be initialized, the others are queried
+var sourcesErr error
+
+// printFederated runs the query against every selected profile and prints the merged notes.
+// The notes found are printed even when some profiles fail, their errors are returned.
+func printFederated(cmd *cobra.Command, query func(s *storage.NoteService) (interface{}, error)) error {
+	notes, err := storage.Federate(noteSources, query)
+	if printErr := printNotes(cmd, notes); printErr != nil {
+		return printErr
+	}
+	return federatedError(err)
+}
+
+// printFederatedNote prints the note with the ID from the profiles that have it
+func printFederatedNote(cmd *cobra.Command, id string) error {
+	notes, err := storage.Federate(noteSources, func(s *storage.NoteService) (interface{}, error) {
+		note, err := s.Get(cmd.Context(), id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return note, err
+	})
+	if len(notes) == 0 && err == nil && sourcesErr == nil {
+		return fmt.Errorf("note %s %w", id, storage.ErrNotFound)
+	}
+	if printErr := printNotes(cmd, notes); printErr != nil {
+		return printErr
+	}
+	return federatedError(err)
+}
+
+// federatedError returns the errors of the profiles that failed, if any
+func federatedError(err error) error {
+	if err = errors.Join(sourcesErr, err); err != nil {
+		return fmt.Errorf("error while querying profiles: %w", err)
+	}
+	return nil
+}
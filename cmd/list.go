@@ -1,48 +1,71 @@
 package cmd
 
 import (
-	"github.com/carloscastrojumo/remindme/pkg/output"
-	"github.com/fatih/color"
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/storage"
 	"github.com/spf13/cobra"
 )
 
 var listCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
-	Short:   "List notes to the database",
-	Long:    `Add new note to the database`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Short:   "List the notes",
+	Long: `List the notes, grouped by their tags. --tags only lists the notes with any of the tags
+and --id a single note, given by its ID or the start of it.
+Notes are numbered when more than one is shown, --copy=N copies the note numbered N.`,
+	Annotations: map[string]string{federated: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		tags, _ := cmd.Flags().GetStringArray("tags")
 		id, _ := cmd.Flags().GetString("id")
 
+		if len(noteSources) > 0 {
+			if id != "" {
+				return printFederatedNote(cmd, id)
+			}
+			return printFederated(cmd, func(s *storage.NoteService) (interface{}, error) {
+				if len(tags) > 0 {
+					return s.GetByTags(cmd.Context(), tags)
+				}
+				return s.GetAll(cmd.Context())
+			})
+		}
+
 		if id != "" {
-			if note, err := noteService.Get(id); err != nil {
-				color.Red("Error: %s", err)
-			} else {
-				output.Print(note)
+			note, err := noteService.Get(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			if err := printNotes(cmd, note); err != nil {
+				return err
 			}
 		}
 
 		if len(tags) > 0 {
-			notes, err := noteService.GetByTags(tags)
+			notes, err := noteService.GetByTags(cmd.Context(), tags)
 			if err != nil {
-				color.Red("Error while getting notes by tags: %s", err)
+				return fmt.Errorf("error while getting notes by tags: %w", err)
+			}
+			if err := printNotes(cmd, notes); err != nil {
+				return err
 			}
-			output.Print(notes)
 		}
 
 		if len(tags) == 0 && id == "" {
-			notes, err := noteService.GetAll()
+			notes, err := noteService.GetAll(cmd.Context())
 			if err != nil {
-				color.Red("Error while getting all notes: %s", err)
+				return fmt.Errorf("error while getting all notes: %w", err)
 			}
-			output.Print(notes)
+			return printNotes(cmd, notes)
 		}
+		return nil
 	},
 }
 
 func init() {
-	listCmd.Flags().StringArray("tags", []string{}, "Tags to add to the note")
-	listCmd.Flags().String("id", "", "ID of the note")
+	listCmd.Flags().StringArrayP("tags", "t", []string{}, "List the notes with any of the tags")
+	listCmd.Flags().String("id", "", "ID of the note to show")
+	addOutputFlag(listCmd)
+	listCmd.PersistentFlags().Bool("all-profiles", false, "List the notes of every profile")
 	rootCmd.AddCommand(listCmd)
 }
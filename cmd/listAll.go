@@ -1,24 +1,33 @@
 package cmd
 
 import (
-	"github.com/carloscastrojumo/remindme/pkg/output"
-	"github.com/fatih/color"
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/storage"
 	"github.com/spf13/cobra"
 )
 
 var listAllCmd = &cobra.Command{
-	Use:   "all",
-	Short: "List all notes in the database",
-	Long:  "List all notes in the database",
-	Run: func(cmd *cobra.Command, args []string) {
-		notes, err := noteService.GetAll()
+	Use:         "all",
+	Short:       "List all notes in the database",
+	Long:        "List all notes in the database",
+	Annotations: map[string]string{federated: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(noteSources) > 0 {
+			return printFederated(cmd, func(s *storage.NoteService) (interface{}, error) {
+				return s.GetAll(cmd.Context())
+			})
+		}
+
+		notes, err := noteService.GetAll(cmd.Context())
 		if err != nil {
-			color.Red("Error while getting notes by tags: %s", err)
+			return fmt.Errorf("error while getting all notes: %w", err)
 		}
-		output.Print(notes)
+		return printNotes(cmd, notes)
 	},
 }
 
 func init() {
+	addOutputFlag(listAllCmd)
 	listCmd.AddCommand(listAllCmd)
 }
@@ -1,24 +1,54 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/carloscastrojumo/remindme/pkg/output"
-	"github.com/fatih/color"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
 	"github.com/spf13/cobra"
 )
 
 var listTags = &cobra.Command{
-	Use:   "tags",
-	Short: "List all tags available",
-	Long:  "List all tags available",
-	Run: func(cmd *cobra.Command, args []string) {
-		tags, err := noteService.GetTags()
+	Use:         "tags",
+	Short:       "List all tags available",
+	Long:        "List all tags available",
+	Annotations: map[string]string{federated: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(noteSources) > 0 {
+			notes, err := storage.Federate(noteSources, func(s *storage.NoteService) (interface{}, error) {
+				return s.GetAll(cmd.Context())
+			})
+
+			tags := []string{}
+			for _, note := range notes {
+				for _, tag := range note.Tags {
+					if !containsString(tags, tag) {
+						tags = append(tags, tag)
+					}
+				}
+			}
+			output.PrintTags(tags)
+			return federatedError(err)
+		}
+
+		tags, err := noteService.GetTags(cmd.Context())
 		if err != nil {
-			color.Red("Error while getting notes by tags: %s", err)
+			return fmt.Errorf("error while getting tags: %w", err)
 		}
 		output.PrintTags(tags)
+		return nil
 	},
 }
 
 func init() {
 	listCmd.AddCommand(listTags)
 }
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
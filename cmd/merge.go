@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/merge"
+	"github.com/carloscastrojumo/remindme/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var mergeOutput string
+
+var mergeOnConflict string
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge [base] [ours] [theirs]",
+	Short: "Merge two versions of a YAML data file",
+	Long: `Merge two versions of a YAML data file changed from a common base, e.g. as a git merge driver.
+Notes are matched by ID. Notes added, deleted or changed on one side are applied, notes changed
+on both sides are merged field by field, tags are merged as sets and uses are added up.
+The result is written to the ours file, unless --output is given.
+
+Fields changed differently on both sides are conflicts, solved with --on-conflict:
+  prompt  ask for each conflict (the default in a terminal)
+  ours    keep our value
+  theirs  take their value
+  fail    keep our value, report the conflicts and exit with an error (the default otherwise)
+
+To use it as the git merge driver of data.yaml:
+  git config merge.rmm.name "remindme notes"
+  git config merge.rmm.driver "rmm merge %O %A %B"
+  echo "data.yaml merge=rmm" >> .gitattributes`,
+	Args:        cobra.ExactArgs(3),
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// encrypted data files are merged decrypted and the result encrypted again
+		encrypted := false
+		versions := make([][]byte, len(args))
+		for i, name := range args {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			if crypt.IsEncryptedFile(data) {
+				encrypted = true
+				cipher, err := config.Cipher()
+				if err != nil {
+					return err
+				}
+				if data, err = cipher.DecryptFile(data); err != nil {
+					return fmt.Errorf("cannot decrypt %s: %w", name, err)
+				}
+			}
+			versions[i] = data
+		}
+
+		_, result, err := merge.Files(versions[0], versions[1], versions[2])
+		if err != nil {
+			return err
+		}
+
+		onConflict := mergeOnConflict
+		if onConflict == "" {
+			onConflict = "fail"
+			if prompt.IsInteractive() {
+				onConflict = "prompt"
+			}
+		}
+
+		unresolved := 0
+		for i := range result.Conflicts {
+			conflict := &result.Conflicts[i]
+			for _, field := range conflict.Fields {
+				switch onConflict {
+				case "ours":
+				case "theirs":
+					conflict.Resolve(field, conflict.Theirs[field])
+				case "prompt":
+					choice, err := prompt.ForChoice(
+						fmt.Sprintf("Note %s (%s): %s changed on both sides", conflict.ID, conflict.Ours.Command(), field),
+						[]string{"ours: " + fieldValue(conflict.Ours[field]), "theirs: " + fieldValue(conflict.Theirs[field])},
+					)
+					if err != nil {
+						return err
+					}
+					if choice == 1 {
+						conflict.Resolve(field, conflict.Theirs[field])
+					}
+				case "fail":
+					unresolved++
+					logger.Warn("Conflict in note %s (%s): %s", conflict.ID, conflict.Ours.Command(), field)
+					logger.Warn("    ours:   %s", fieldValue(conflict.Ours[field]))
+					logger.Warn("    theirs: %s", fieldValue(conflict.Theirs[field]))
+				default:
+					return usageError("unknown conflict resolution %q (prompt, ours, theirs, fail)", onConflict)
+				}
+			}
+		}
+
+		data, err := merge.Marshal(result.Notes)
+		if err != nil {
+			return err
+		}
+		if encrypted {
+			cipher, err := config.Cipher()
+			if err != nil {
+				return err
+			}
+			if data, err = cipher.EncryptFile(data); err != nil {
+				return err
+			}
+		}
+
+		output := mergeOutput
+		if output == "" {
+			output = args[1]
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return err
+		}
+
+		if unresolved > 0 {
+			return errdefs.Mark(fmt.Errorf("%d conflict(s) found, our values were kept", unresolved), errdefs.ErrConflict)
+		}
+		logger.Success("Notes merged into %s", output)
+		return nil
+	},
+}
+
+// fieldValue shows the value of a note field in a conflict
+func fieldValue(value interface{}) string {
+	if value == nil {
+		return "(removed)"
+	}
+	return fmt.Sprint(value)
+}
+
+func init() {
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", "", "File to write the result to (defaults to the ours file)")
+	mergeCmd.Flags().StringVar(&mergeOnConflict, "on-conflict", "", "How to solve conflicts: prompt, ours, theirs or fail")
+	rootCmd.AddCommand(mergeCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/clipboard"
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlag adds the flags selecting the output format of the notes
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "text", "Output format (text, json)")
+	cmd.Flags().Bool("reveal", false, "Show the commands of secret notes")
+	cmd.Flags().Int("copy", 0, "Copy the command of the note numbered N to the clipboard, e.g. --copy=2, the first one without N")
+	cmd.Flags().Lookup("copy").NoOptDefVal = "1"
+}
+
+// printNotes prints the notes in the selected output format, then copies the one chosen with --copy.
+// Without --copy, the only note shown is copied when clipboard.copy is set in the config file.
+func printNotes(cmd *cobra.Command, notes interface{}) error {
+	format, _ := cmd.Flags().GetString("output")
+	reveal, _ := cmd.Flags().GetBool("reveal")
+
+	var shown []output.Note
+	switch format {
+	case "json":
+		shown = output.PrintJSON(notes, reveal)
+	case "text", "":
+		shown = output.Print(notes, reveal)
+	default:
+		return usageError("output format %q not supported (text, json)", format)
+	}
+
+	if !cmd.Flags().Changed("copy") {
+		if config.CopyByDefault() && len(shown) == 1 {
+			if err := copyNote(cmd, shown[0]); err != nil {
+				logger.Warn("Note %s not copied: %s", shown[0].ID, err)
+			}
+		}
+		return nil
+	}
+
+	n, _ := cmd.Flags().GetInt("copy")
+	switch {
+	case n == 0:
+		return nil
+	case n < 0 || n > len(shown):
+		return usageError("can't copy note %d, %d notes shown", n, len(shown))
+	}
+	return copyNote(cmd, shown[n-1])
+}
+
+// copyNote copies the command of the note to the clipboard.
+// A copied note counts as used, which ranks it higher in searches.
+func copyNote(cmd *cobra.Command, note output.Note) error {
+	if err := clipboard.Copy(note.Command, config.ClipboardMethod()); err != nil {
+		return err
+	}
+	logger.Info("Note %s copied to the clipboard", note.ID)
+
+	if noteService != nil {
+		if err := noteService.MarkUsed(cmd.Context(), note.ID); err != nil {
+			logger.Warn("Error while counting the use of note %s: %s", note.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:         "profile",
+	Short:       "Manage storage profiles",
+	Long:        `Manage storage profiles, each profile is a named storage configuration (e.g. a personal YAML file and a shared Mongo collection)`,
+	Annotations: map[string]string{noStorage: ""},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+}
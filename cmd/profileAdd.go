@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var newProfile config.Profile
+
+var profileAddCmd = &cobra.Command{
+	Use:         "add [profile]",
+	Short:       "Add a new profile",
+	Long:        "Add a new profile, if no storage flags are given the configuration is prompted",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if newProfile.StorageType == "" {
+			newProfile = config.PromptProfile()
+		}
+
+		if err := config.AddProfile(args[0], newProfile); err != nil {
+			return err
+		}
+		logger.Success("Profile %s added", args[0])
+		return nil
+	},
+}
+
+func init() {
+	profileAddCmd.Flags().StringVar(&newProfile.StorageType, "storage", "", "Storage type of the profile (mongo, yaml, http)")
+	profileAddCmd.Flags().StringVar(&newProfile.Yaml.Name, "yaml-file", "", "YAML data file")
+	profileAddCmd.Flags().StringVar(&newProfile.Mongo.Host, "mongo-host", "localhost", "Mongo host")
+	profileAddCmd.Flags().IntVar(&newProfile.Mongo.Port, "mongo-port", 27017, "Mongo port")
+	profileAddCmd.Flags().StringVar(&newProfile.Mongo.Database, "mongo-database", "notes", "Mongo database")
+	profileAddCmd.Flags().StringVar(&newProfile.Mongo.Collection, "mongo-collection", "notes", "Mongo collection")
+	profileAddCmd.Flags().StringVar(&newProfile.Mongo.URI, "mongo-uri", "", "Mongo connection string (mongodb:// or mongodb+srv://), replaces host and port")
+	profileAddCmd.Flags().StringVar(&newProfile.Mongo.Username, "mongo-username", "", "Mongo username")
+	profileAddCmd.Flags().StringVar(&newProfile.Mongo.PasswordEnv, "mongo-password-env", "", "Environment variable holding the Mongo password")
+	profileAddCmd.Flags().StringVar(&newProfile.Mongo.PasswordFile, "mongo-password-file", "", "File holding the Mongo password")
+	profileAddCmd.Flags().BoolVar(&newProfile.Mongo.TLS, "mongo-tls", false, "Connect to Mongo using TLS")
+	profileAddCmd.Flags().StringVar(&newProfile.Mongo.TLSCAFile, "mongo-tls-ca-file", "", "CA certificates used to verify the Mongo server")
+	profileAddCmd.Flags().StringVar(&newProfile.HTTP.URL, "http-url", "", "URL of the rmm serve server, e.g. http://notes.internal:8080")
+	profileAddCmd.Flags().StringVar(&newProfile.HTTP.TokenEnv, "http-token-env", "", "Environment variable holding the server token")
+	profileAddCmd.Flags().StringVar(&newProfile.HTTP.TokenFile, "http-token-file", "", "File holding the server token")
+	profileCmd.AddCommand(profileAddCmd)
+}
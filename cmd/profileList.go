@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var profileListCmd = &cobra.Command{
+	Use:         "list",
+	Aliases:     []string{"ls"},
+	Short:       "List all profiles",
+	Long:        "List all profiles, the profile in use is marked with '*'",
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		current := config.ResolveProfile(profileName)
+		invalid := 0
+
+		color.Yellow("----- Available profiles -----")
+		for _, name := range config.ProfileNames() {
+			profile, err := config.GetProfile(name)
+			if err != nil {
+				logger.Error("%s: %s", name, err)
+				invalid++
+				continue
+			}
+
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			color.Green("%s %s (%s)", marker, name, profile.StorageType)
+		}
+
+		if invalid > 0 {
+			return fmt.Errorf("%d profile(s) not valid", invalid)
+		}
+		return nil
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd)
+}
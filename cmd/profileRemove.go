@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var profileRemoveCmd = &cobra.Command{
+	Use:         "remove [profile]",
+	Aliases:     []string{"rm"},
+	Short:       "Remove a profile",
+	Long:        "Remove a profile from the configuration, the data of the profile is kept",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.RemoveProfile(args[0]); err != nil {
+			return err
+		}
+		logger.Success("Profile %s removed", args[0])
+		return nil
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileRemoveCmd)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var profileUseCmd = &cobra.Command{
+	Use:         "use [profile]",
+	Short:       "Set the profile used by default",
+	Long:        "Set the profile used by default, it can be overridden with --profile or $" + config.ProfileEnv,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{noStorage: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.UseProfile(args[0]); err != nil {
+			return err
+		}
+		logger.Success("Using profile %s", args[0])
+		return nil
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileUseCmd)
+}
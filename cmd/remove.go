@@ -1,7 +1,9 @@
 package cmd
 
 import (
-	"github.com/fatih/color"
+	"fmt"
+
+	"github.com/carloscastrojumo/remindme/pkg/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -9,30 +11,33 @@ var removeCmd = &cobra.Command{
 	Use:   "rm",
 	Short: "Remove note from the database",
 	Long:  `Remove note from the database`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, _ := cmd.Flags().GetString("id")
 		tags, _ := cmd.Flags().GetStringArray("tags")
 
+		if id == "" && len(tags) == 0 {
+			return usageError("give the --id of the note or the --tags of the notes to remove")
+		}
+
 		if id != "" {
-			if err := noteService.Remove(id); err != nil {
-				color.Red("Error: %s", err)
-			} else {
-				color.Green("Note %s deleted", id)
+			if err := noteService.Remove(cmd.Context(), id); err != nil {
+				return err
 			}
+			logger.Success("Note %s deleted", id)
 		}
 
 		if len(tags) > 0 {
-			if err := noteService.RemoveByTags(tags); err != nil {
-				color.Red("Error while deleting notes by tags: %s", err)
-			} else {
-				color.Green("Notes with tags %s deleted", tags)
+			if err := noteService.RemoveByTags(cmd.Context(), tags); err != nil {
+				return fmt.Errorf("error while deleting notes by tags: %w", err)
 			}
+			logger.Success("Notes with tags %s deleted", tags)
 		}
+		return nil
 	},
 }
 
 func init() {
 	removeCmd.Flags().String("id", "", "ID of the note to remove")
-	removeCmd.Flags().StringArray("tags", []string{}, "Remove all notes from tags")
+	removeCmd.Flags().StringArrayP("tags", "t", []string{}, "Remove all notes from tags")
 	rootCmd.AddCommand(removeCmd)
 }
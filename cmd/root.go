@@ -1,34 +1,181 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
 	"github.com/carloscastrojumo/remindme/pkg/storage"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+// noStorage is the annotation set on commands that don't use the note storage
+const noStorage = "noStorage"
+
+// federated is the annotation set on read commands that can query several profiles at once
+const federated = "federated"
+
+// closeTimeout bounds the time spent closing storage connections on exit
+const closeTimeout = 5 * time.Second
+
 var noteService *storage.NoteService
 
+// noteSources holds the note services of every profile when several profiles are queried
+var noteSources []storage.Source
+
+var profileName string
+
+var configFile string
+
+var quiet bool
+
+var verbose bool
+
+var noColor bool
+
 var rootCmd = &cobra.Command{
 	Use:   "remindme",
 	Short: "remindme - a simple CLI to remind you about notes",
 	Long: `remindme - a simple CLI to remind you about notes
    
 One can use stringer to modify or inspect strings straight from the terminal`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		setupOutput()
+
+		if err := config.InitConfig(configFile); err != nil {
+			return err
+		}
+
+		if _, ok := cmd.Annotations[noStorage]; ok {
+			return nil
+		}
+
+		if !config.IsConfigured() {
+			if err := config.PromptConfigFile(); err != nil {
+				return err
+			}
+		}
+
+		profiles := selectedProfiles(cmd)
+		if len(profiles) > 1 {
+			if _, ok := cmd.Annotations[federated]; !ok {
+				return fmt.Errorf("%s can only use one profile at a time", cmd.CommandPath())
+			}
+			sources, err := config.GetNoteSources(cmd.Context(), profiles)
+			if err != nil && len(sources) == 0 {
+				return err
+			}
+			// the other profiles are queried, the error is returned after their notes are printed
+			noteSources, sourcesErr = sources, err
+			return nil
+		}
+
+		service, err := config.GetNoteService(cmd.Context(), profiles[0])
+		if err != nil {
+			return err
+		}
+		noteService = service
+		return nil
+	},
+	// unknown commands are arguments of the root command
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("remindme - a simple CLI to remind you about notes")
 	},
+	// errors are printed by Execute, with the usage hint only for usage errors
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file (defaults to $"+config.ConfigEnv+" or ~/.config/remindme/config.yaml)")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return errdefs.Mark(err, errdefs.ErrUsage)
+	})
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print results and errors")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Also print details, e.g. the storage used")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Print without colors (also disabled by $NO_COLOR)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Profile to use, read commands accept a comma separated list (defaults to $"+config.ProfileEnv+" or the current profile)")
+}
+
+// setupOutput applies the --quiet, --verbose and --no-color flags. Results are printed on the
+// standard output, everything else is logged on the standard error.
+func setupOutput() {
+	switch {
+	case quiet:
+		logger.SetLevel(logger.LevelError)
+	case verbose:
+		logger.SetLevel(logger.LevelDebug)
+	}
+
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		color.NoColor = true
+		logger.DisableColor()
+	}
+}
+
+// selectedProfiles returns the profiles given by --all-profiles or a comma separated --profile
+func selectedProfiles(cmd *cobra.Command) []string {
+	if all, _ := cmd.Flags().GetBool("all-profiles"); all {
+		return config.ProfileNames()
+	}
+
+	profiles := []string{}
+	for _, name := range strings.Split(config.ResolveProfile(profileName), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			profiles = append(profiles, name)
+		}
+	}
+	if len(profiles) == 0 {
+		profiles = append(profiles, config.DefaultProfile)
+	}
+	return profiles
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// rmm exits with the code of the error, see exitCodes.
 func Execute() {
-	config.InitConfig()
-	noteService = config.GetNoteService()
+	markUsageErrors(rootCmd)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	stop()
+	closeStorage()
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Whoops. There was an error while executing your CLI '%s'", err)
-		os.Exit(1)
+	// commands run by rmm run give their exit status
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// -1 when the command was killed by a signal
+		os.Exit(max(exitErr.ExitCode(), 1))
+	}
+
+	if err != nil {
+		logger.Error("Error: %s", err)
+		if errors.Is(err, errdefs.ErrUsage) {
+			logger.Info("Run '%s --help' for usage.", cmd.CommandPath())
+		}
+	}
+	os.Exit(exitCode(err))
+}
+
+// closeStorage releases the storage connections, bounded by closeTimeout
+func closeStorage() {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	if noteService != nil {
+		noteService.Close(ctx)
+	}
+	for _, source := range noteSources {
+		source.Service.Close(ctx)
 	}
 }
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [id] [-- args...]",
+	Short: "Run the command of a note",
+	Long: `Run the command of a note with its shell or language, your shell when it has none.
+Multi-line commands are run as a whole script. Arguments after -- are given to the script,
+e.g. as $1, $2... in shell scripts. rmm exits with the exit status of the command.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		note, err := noteService.GetNote(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := noteService.MarkUsed(cmd.Context(), note.ID); err != nil {
+			logger.Warn("Error while counting the use of note %s: %s", note.ID, err)
+		}
+
+		// the command reports its own errors, rmm only exits with its status
+		return runner.Command(cmd.Context(), note.Command, note.Shell, args[1:]).Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+}
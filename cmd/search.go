@@ -1,42 +1,64 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
 	"strings"
 
-	"github.com/carloscastrojumo/remindme/pkg/output"
-	"github.com/fatih/color"
+	"github.com/carloscastrojumo/remindme/pkg/search"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
 var searchCmd = &cobra.Command{
-	Use:   "search [word-to-search] [flags]",
+	Use:   "search [query] [flags]",
 	Short: "Search the notes in the database",
-	Long:  `Search the notes in the database, by default it searches in tags, commands, and descriptions. If a flag is specified it will only search in those.`,
+	Long: `Search the notes in the database, by default it searches in tags, commands, and descriptions. If a flag is specified unqualified terms only search in those.
+
+Results are ranked best first: matches in commands rank above tags and descriptions, and notes used more
+often rank higher. Words with typos or abbreviations also match, at a lower rank, unless --exact is given.
+
+Terms are combined with AND, use OR between terms for alternatives. Searches are case insensitive unless --case-sensitive is given.
+  kubectl pods            notes containing both words
+  tag:k8s cmd:delete      terms qualified with a field (tag, cmd, desc)
+  "exact phrase"          words kept together
+  -deprecated             notes not containing the word
+  /get .*pods/i           a regular expression
+  tag:git OR tag:github   notes matching either term`,
+	Annotations: map[string]string{federated: ""},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var searchLocations []string
 
 		cmd.Flags().Visit(func(f *pflag.Flag) {
-			searchLocations = append(searchLocations, f.Name)
+			switch f.Name {
+			case "tags", "command", "description":
+				searchLocations = append(searchLocations, f.Name)
+			}
 		})
 
-		if len(searchLocations) == 0 {
-			searchLocations = append(searchLocations, "command", "description", "tags")
-		}
-
 		if len(args) < 1 {
-			return errors.New("the word-to-search argument is required")
+			return usageError("the query argument is required")
 		}
 
-		words := strings.Split(args[0], " ")
-		notes, err := noteService.Search(words, searchLocations)
+		caseSensitive, _ := cmd.Flags().GetBool("case-sensitive")
+		query, err := search.Parse(strings.Join(args, " "), searchLocations, caseSensitive)
 		if err != nil {
-			color.Red("Error while getting notes by tags: %s", err)
+			return usageError("invalid query: %w", err)
 		}
-		output.Print(notes)
+		exact, _ := cmd.Flags().GetBool("exact")
+		query.Fuzzy = !exact
 
-		return nil
+		if len(noteSources) > 0 {
+			return printFederated(cmd, func(s *storage.NoteService) (interface{}, error) {
+				return s.Search(cmd.Context(), query)
+			})
+		}
+
+		notes, err := noteService.Search(cmd.Context(), query)
+		if err != nil {
+			return fmt.Errorf("error while searching notes: %w", err)
+		}
+		return printNotes(cmd, notes)
 	},
 }
 
@@ -44,5 +66,9 @@ func init() {
 	searchCmd.Flags().BoolP("tags", "t", false, "Search in tags")
 	searchCmd.Flags().BoolP("command", "c", false, "Search in commands")
 	searchCmd.Flags().BoolP("description", "d", false, "Search in description")
+	searchCmd.Flags().Bool("case-sensitive", false, "Match the case of the query")
+	searchCmd.Flags().Bool("exact", false, "Only match the words exactly, without typos")
+	addOutputFlag(searchCmd)
+	searchCmd.Flags().Bool("all-profiles", false, "Search the notes of every profile")
 	rootCmd.AddCommand(searchCmd)
 }
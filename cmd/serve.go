@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/server"
+	"github.com/carloscastrojumo/remindme/pkg/web"
+	"github.com/spf13/cobra"
+)
+
+// serveTokenEnv is the environment variable holding the token of rmm serve
+const serveTokenEnv = config.EnvPrefix + "_SERVE_TOKEN"
+
+// shutdownTimeout bounds the time given to the requests in progress when the server stops
+const shutdownTimeout = 5 * time.Second
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the notes over a REST API",
+	Long: `Serve the notes of the profile over a REST API with JSON bodies, as printed by 'rmm list -o json'.
+The API is described by /api/openapi.yaml. Stop the server with Ctrl-C.
+
+A web UI browsing, searching and editing the notes is served on /, unless --no-ui is given.
+
+Requests must send a token in an "Authorization: Bearer <token>" header. It's read from $` + serveTokenEnv + `
+or from --token-file, a new token is generated and printed each time the server starts when none is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		tokenFile, _ := cmd.Flags().GetString("token-file")
+		noUI, _ := cmd.Flags().GetBool("no-ui")
+
+		token, err := serveToken(tokenFile)
+		if err != nil {
+			return err
+		}
+		generated := token == ""
+		if generated {
+			if token, err = generateToken(); err != nil {
+				return err
+			}
+		}
+
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			return err
+		}
+
+		api := server.New(noteService, token)
+		if !noUI {
+			api.Handle("GET /", web.Handler())
+		}
+		srv := &http.Server{
+			Handler:           api,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			<-cmd.Context().Done()
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			srv.Shutdown(ctx)
+		}()
+
+		logger.Info("Serving the notes on http://%s/api/notes", listener.Addr())
+		if generated {
+			logger.Info("Token of the requests, generated as none was given: %s", token)
+		}
+		if !noUI {
+			logger.Info("Web UI on http://%s/#token=%s", listener.Addr(), token)
+		}
+		if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		logger.Info("Server stopped")
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("listen", "127.0.0.1:8080", "Address to listen on, host:port")
+	serveCmd.Flags().String("token-file", "", "File holding the token requests must send, overrides $"+serveTokenEnv)
+	serveCmd.Flags().Bool("no-ui", false, "Only serve the API, without the web UI")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// serveToken returns the token of the server, read from the file when it's given
+func serveToken(tokenFile string) (string, error) {
+	if tokenFile == "" {
+		return os.Getenv(serveTokenEnv), nil
+	}
+
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("error while reading token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// generateToken returns a random token for a server started without one
+func generateToken() (string, error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the notes with a git remote",
+	Long: `Sync the notes with a git remote, e.g. a dotfiles repository. The directory of the YAML data file
+becomes a git repository if it isn't in one. Pending changes are committed, the changes of the remote
+are merged note by note and the result is pushed.
+
+Set the remote, a URL or the name of a remote of the repository, and optionally the branch (main):
+
+  rmm config set yaml.sync.remote git@github.com:me/notes.git
+  rmm config set yaml.sync.branch main
+
+Once a remote is set every change to the notes is committed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := noteService.Sync(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		for _, conflict := range result.Conflicts {
+			logger.Warn("Note %s (%s) was changed on both sides, the local %s was kept", conflict.ID, conflict.Ours.Command(), strings.Join(conflict.Fields, ", "))
+		}
+
+		switch {
+		case result.Pulled && result.Pushed:
+			logger.Success("Notes synced with %s/%s, changes received and sent", result.Remote, result.Branch)
+		case result.Pulled:
+			logger.Success("Notes synced with %s/%s, changes received", result.Remote, result.Branch)
+		case result.Pushed:
+			logger.Success("Notes synced with %s/%s, changes sent", result.Remote, result.Branch)
+		default:
+			logger.Success("Notes are up to date with %s/%s", result.Remote, result.Branch)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/prompt"
+	"github.com/carloscastrojumo/remindme/pkg/tui"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse and edit the notes in a full-screen terminal interface",
+	Long: `Browse and edit the notes in a full-screen terminal interface: the tags on the left, the notes
+of the selected tag in the middle and the selected note on the right.
+
+Keys:
+  a      add a note
+  e      edit the selected note
+  d      delete it
+  t      change its tags
+  c      copy its command, also Enter
+  r      run its command, the interface is back once it's done
+  v      show or hide the commands of secret notes
+  /      filter the notes with a query of 'rmm search', Esc clears the filter
+  tab    switch between the tags and the notes
+  ?      show the keys
+  q      quit, also Ctrl-C`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !prompt.IsInteractive() || !isatty.IsTerminal(os.Stdout.Fd()) {
+			return usageError("rmm ui needs a terminal, use 'rmm list' or 'rmm search' otherwise")
+		}
+		return tui.New(cmd.Context(), noteService, config.ClipboardMethod()).Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+}
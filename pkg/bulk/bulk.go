@@ -0,0 +1,223 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/output"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Input formats
+const (
+	// Command is a single command, e.g. a line of the shell history
+	Command = "command"
+	// JSON is a JSON array of notes or a single note, e.g. the output of 'rmm list -o json'
+	JSON = "json"
+	// NDJSON is a JSON note per line
+	NDJSON = "ndjson"
+	// YAML is a YAML list of notes or a single note, e.g. a data file
+	YAML = "yaml"
+)
+
+// Formats are the supported input formats
+var Formats = []string{Command, JSON, NDJSON, YAML}
+
+// Note is a note read from the input, other fields such as the ID are ignored
+type Note struct {
+	Command     string   `json:"command" yaml:"command"`
+	Description string   `json:"description" yaml:"description"`
+	Tags        []string `json:"tags" yaml:"tags"`
+	Shell       string   `json:"shell" yaml:"shell"`
+	Secret      bool     `json:"secret" yaml:"secret"`
+}
+
+// Record is a note of the input with the line it starts at, Err is set when it's not valid
+type Record struct {
+	Line int
+	Note Note
+	Err  error
+}
+
+// historyNumber matches the event number shell history lines start with, e.g. "  501  git push"
+var historyNumber = regexp.MustCompile(`^\s*\d+\*?\s+`)
+
+// yamlLine matches the line YAML errors start with
+var yamlLine = regexp.MustCompile(`^line \d+: `)
+
+// Detect guesses the format of the input: JSON when it starts with [ or {, NDJSON when the first line
+// is a whole JSON object, YAML when it's a list or starts with a command key, otherwise a single command
+func Detect(data []byte) string {
+	text := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(text, "["):
+		return JSON
+	case strings.HasPrefix(text, "{"):
+		// a single object can span several lines
+		if first, _, _ := strings.Cut(text, "\n"); strings.HasSuffix(strings.TrimSpace(first), "}") {
+			return NDJSON
+		}
+		return JSON
+	case strings.HasPrefix(text, "---"), strings.HasPrefix(text, "- "), strings.HasPrefix(text, "command:"):
+		return YAML
+	}
+	return Command
+}
+
+// Parse reads the notes of the input. Invalid notes are returned as records with an error,
+// the error is only set when the input can't be read at all.
+func Parse(data []byte, format string) ([]Record, error) {
+	if format == "" {
+		format = Detect(data)
+	}
+
+	var records []Record
+	var err error
+	switch format {
+	case Command:
+		records = parseCommand(data)
+	case JSON:
+		records, err = parseJSON(data)
+	case NDJSON:
+		records, err = parseNDJSON(data)
+	case YAML:
+		records, err = parseYAML(data)
+	default:
+		return nil, fmt.Errorf("input format %q not supported (%s)", format, strings.Join(Formats, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if records[i].Err == nil {
+			records[i].Err = validate(&records[i].Note)
+		}
+	}
+	return records, nil
+}
+
+func parseCommand(data []byte) []Record {
+	command := strings.TrimRight(string(data), "\r\n")
+	if !strings.Contains(command, "\n") {
+		command = strings.TrimSpace(historyNumber.ReplaceAllString(command, ""))
+	}
+	return []Record{{Line: 1, Note: Note{Command: command}}}
+}
+
+func parseJSON(data []byte) ([]Record, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		record := Record{Line: 1}
+		record.Err = decodeJSON(data, &record.Note)
+		return []Record{record}, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	records := []Record{}
+	for decoder.More() {
+		// the offset is before the separator of the element, skip it to find its line
+		offset := decoder.InputOffset()
+		for offset < int64(len(data)) && strings.ContainsRune(", \t\r\n", rune(data[offset])) {
+			offset++
+		}
+		record := Record{Line: bytes.Count(data[:offset], []byte("\n")) + 1}
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			// the rest of the array can't be read
+			return append(records, Record{Line: record.Line, Err: fmt.Errorf("invalid JSON: %w", err)}), nil
+		}
+		record.Err = decodeJSON(raw, &record.Note)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func parseNDJSON(data []byte) ([]Record, error) {
+	records := []Record{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		record := Record{Line: line}
+		record.Err = decodeJSON(text, &record.Note)
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func decodeJSON(data []byte, note *Note) error {
+	if err := json.Unmarshal(data, note); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("invalid %s: expected a %s", typeErr.Field, typeErr.Type)
+		}
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+func parseYAML(data []byte) ([]Record, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return []Record{}, nil
+	}
+
+	nodes := []*yaml.Node{document.Content[0]}
+	if document.Content[0].Kind == yaml.SequenceNode {
+		nodes = document.Content[0].Content
+	}
+
+	records := []Record{}
+	for _, node := range nodes {
+		record := Record{Line: node.Line}
+		if err := node.Decode(&record.Note); err != nil {
+			var typeErr *yaml.TypeError
+			if errors.As(err, &typeErr) {
+				// the line is already given by the record
+				for i, message := range typeErr.Errors {
+					typeErr.Errors[i] = yamlLine.ReplaceAllString(message, "")
+				}
+				err = errors.New(strings.Join(typeErr.Errors, ", "))
+			}
+			record.Err = fmt.Errorf("invalid note: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// validate checks the note has a command and cleans up its tags
+func validate(note *Note) error {
+	if strings.TrimSpace(note.Command) == "" {
+		return errors.New("the command is missing")
+	}
+	// e.g. secret notes listed without --reveal, the command would be lost
+	if note.Command == output.SecretMask {
+		return errors.New("the command is masked, list the secret notes with --reveal to add them back")
+	}
+
+	tags := []string{}
+	for _, tag := range note.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	note.Tags = tags
+	return nil
+}
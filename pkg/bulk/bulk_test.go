@@ -0,0 +1,175 @@
+package bulk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`[{"command": "ls"}]`, JSON},
+		{"  \n[\n  {\"command\": \"ls\"}\n]", JSON},
+		{"{\n  \"command\": \"ls\"\n}", JSON},
+		{`{"command": "ls"}`, NDJSON},
+		{"{\"command\": \"ls\"}\n{\"command\": \"pwd\"}", NDJSON},
+		{"- command: ls\n- command: pwd", YAML},
+		{"---\ncommand: ls", YAML},
+		{"command: ls\ntags: [fs]", YAML},
+		{"ls -la", Command},
+		{"  501  git push", Command},
+		{"-rf", Command},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Detect([]byte(tt.input)); got != tt.want {
+				t.Errorf("Detect() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// summary describes the records, e.g. "1:ls [fs]" or "3:error: the command is missing"
+func summary(records []Record) string {
+	list := []string{}
+	for _, r := range records {
+		if r.Err != nil {
+			list = append(list, fmt.Sprintf("%d:error: %s", r.Line, r.Err))
+			continue
+		}
+		list = append(list, fmt.Sprintf("%d:%s %v", r.Line, r.Note.Command, r.Note.Tags))
+	}
+	return strings.Join(list, "\n")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+	}{
+		{
+			name:   "command",
+			format: Command,
+			input:  "kubectl get pods\n",
+			want:   "1:kubectl get pods []",
+		},
+		{
+			name:   "history line",
+			format: Command,
+			input:  "  501* git push --force\n",
+			want:   "1:git push --force []",
+		},
+		{
+			name:   "multi-line command",
+			format: Command,
+			input:  "cat <<EOF\n  501 lines\nEOF\n",
+			want:   "1:cat <<EOF\n  501 lines\nEOF []",
+		},
+		{
+			name:   "json array",
+			format: JSON,
+			input:  "[\n  {\"command\": \"ls\", \"tags\": [\"fs\", \" \"]},\n\n  {\"command\": \"pwd\"}\n]",
+			want:   "2:ls [fs]\n4:pwd []",
+		},
+		{
+			name:   "json object",
+			format: JSON,
+			input:  "{\n  \"command\": \"ls\",\n  \"secret\": false\n}",
+			want:   "1:ls []",
+		},
+		{
+			name:   "json invalid records",
+			format: JSON,
+			input:  "[\n  {\"command\": \"ls\"},\n  {\"tags\": [\"fs\"]},\n  {\"command\": 1},\n  {\"command\": \"********\"},\n  {\"command\": \"pwd\"}\n]",
+			want: "2:ls []\n3:error: the command is missing\n4:error: invalid command: expected a string\n" +
+				"5:error: the command is masked, list the secret notes with --reveal to add them back\n6:pwd []",
+		},
+		{
+			name:   "json broken array",
+			format: JSON,
+			input:  "[\n  {\"command\": \"ls\"},\n  {\"command\": \"pwd\"\n]",
+			want:   "2:ls []\n3:error: invalid JSON: invalid character ']' after object key:value pair",
+		},
+		{
+			name:   "json not a note",
+			format: JSON,
+			input:  "nope",
+			want:   "1:error: invalid JSON: invalid character 'o' in literal null (expecting 'u')",
+		},
+		{
+			name:   "ndjson",
+			format: NDJSON,
+			input:  "{\"command\": \"ls\"}\n\n{\"command\": \"pwd\", \"tags\": [\"fs\"]}\n",
+			want:   "1:ls []\n3:pwd [fs]",
+		},
+		{
+			name:   "ndjson invalid records",
+			format: NDJSON,
+			input:  "{\"command\": \"ls\"}\n{\"command\": \"pwd\"\n{\"command\": \"********\", \"secret\": true}\n{\"command\": \"  \"}\n",
+			want: "1:ls []\n2:error: invalid JSON: unexpected end of JSON input\n" +
+				"3:error: the command is masked, list the secret notes with --reveal to add them back\n4:error: the command is missing",
+		},
+		{
+			name:   "yaml list",
+			format: YAML,
+			input:  "- command: ls\n  tags: [fs]\n\n- command: |-\n    cat <<EOF\n    hi\n    EOF\n",
+			want:   "1:ls [fs]\n4:cat <<EOF\nhi\nEOF []",
+		},
+		{
+			name:   "yaml data file note",
+			format: YAML,
+			input:  "command: ls\nid: \"123\"\nuses: 3\n",
+			want:   "1:ls []",
+		},
+		{
+			name:   "yaml invalid records",
+			format: YAML,
+			input:  "- command: ls\n- tags: [fs]\n- command: [a, b]\n- command: \"********\"\n  secret: true\n",
+			want: "1:ls []\n2:error: the command is missing\n3:error: invalid note: cannot unmarshal !!seq into string\n" +
+				"4:error: the command is masked, list the secret notes with --reveal to add them back",
+		},
+		{
+			name:  "detected",
+			input: "- command: ls\n- command: pwd\n",
+			want:  "1:ls []\n2:pwd []",
+		},
+		{
+			name:  "detected masked command",
+			input: "********",
+			want:  "1:error: the command is masked, list the secret notes with --reveal to add them back",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Parse([]byte(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("Parse() = %v", err)
+			}
+			if got := summary(records); got != tt.want {
+				t.Errorf("Parse() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUnreadable(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{YAML, "- command: ls\n command: pwd\n"},
+		{"csv", "ls"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.input, func(t *testing.T) {
+			if records, err := Parse([]byte(tt.input), tt.format); err == nil {
+				t.Errorf("Parse() = %s, want an error", summary(records))
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/mattn/go-isatty"
+)
+
+// Ways of copying text, set with the clipboard.method configuration key
+const (
+	// Auto uses OSC 52 in SSH sessions, the system clipboard when there's one, then tmux or OSC 52
+	Auto = "auto"
+	// System uses the clipboard of the desktop, through pbcopy, xsel, xclip, wl-copy or termux-clipboard-set
+	System = "system"
+	// OSC52 asks the terminal to copy the text with an escape sequence, it works over SSH
+	OSC52 = "osc52"
+	// Tmux loads the text in a tmux buffer, paste it with prefix + ]
+	Tmux = "tmux"
+)
+
+// Methods are the ways of copying text
+var Methods = []string{Auto, System, OSC52, Tmux}
+
+// ErrUnavailable is returned when there's no way of copying text, e.g. no clipboard tool and no terminal
+var ErrUnavailable = errors.New("no clipboard available")
+
+// Copy copies the text with the method, Auto when it's empty
+func Copy(text string, method string) error {
+	switch Resolve(method) {
+	case System:
+		return clipboard.WriteAll(text)
+	case OSC52:
+		return osc52(text)
+	case Tmux:
+		return tmux(text)
+	case "":
+		return ErrUnavailable
+	}
+	return fmt.Errorf("clipboard method %q not supported (%s)", method, strings.Join(Methods, ", "))
+}
+
+// Resolve returns the method used to copy text, the method found for Auto.
+// It returns an empty string when Auto finds none.
+func Resolve(method string) string {
+	if method != "" && method != Auto {
+		return method
+	}
+
+	switch {
+	case remote() && hasTerminal():
+		return OSC52
+	case SystemAvailable():
+		return System
+	case os.Getenv("TMUX") != "":
+		return Tmux
+	case hasTerminal():
+		return OSC52
+	}
+	return ""
+}
+
+// SystemAvailable reports whether a clipboard tool of the desktop is installed
+func SystemAvailable() bool {
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return !clipboard.Unsupported
+}
+
+// remote reports whether rmm runs in an SSH session, where the system clipboard isn't the one of the user
+func remote() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_CLIENT") != ""
+}
+
+// hasTerminal reports whether escape sequences can be written to a terminal
+func hasTerminal() bool {
+	tty := terminal()
+	if tty == nil {
+		return false
+	}
+	tty.Close()
+	return true
+}
+
+// terminal returns the terminal the escape sequences are written to, nil when there's none
+func terminal() io.WriteCloser {
+	if runtime.GOOS != "windows" {
+		if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+			return tty
+		}
+	}
+	if isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()) {
+		return nopCloser{os.Stderr}
+	}
+	return nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// osc52 writes the OSC 52 escape sequence, wrapped for tmux or screen so they pass it to the terminal
+func osc52(text string) error {
+	tty := terminal()
+	if tty == nil {
+		return errors.New("can't copy with OSC 52, not running in a terminal")
+	}
+	defer tty.Close()
+
+	sequence := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	switch {
+	case os.Getenv("TMUX") != "":
+		// needs "set -g allow-passthrough on" since tmux 3.3
+		sequence = "\x1bPtmux;" + strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		sequence = "\x1bP" + sequence + "\x1b\\"
+	}
+
+	_, err := io.WriteString(tty, sequence)
+	return err
+}
+
+// tmux loads the text in the tmux paste buffer
+func tmux(text string) error {
+	cmd := exec.Command("tmux", "load-buffer", "-")
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(out)); message != "" {
+			return fmt.Errorf("tmux load-buffer: %s", message)
+		}
+		return fmt.Errorf("tmux load-buffer: %w", err)
+	}
+	return nil
+}
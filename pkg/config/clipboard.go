@@ -0,0 +1,19 @@
+package config
+
+import (
+	"github.com/carloscastrojumo/remindme/pkg/clipboard"
+	"github.com/spf13/viper"
+)
+
+// CopyByDefault reports whether a note is copied when it's the only one shown, without --copy
+func CopyByDefault() bool {
+	return viper.GetBool("clipboard.copy")
+}
+
+// ClipboardMethod returns how notes are copied: auto, system, osc52 or tmux
+func ClipboardMethod() string {
+	if method := viper.GetString("clipboard.method"); method != "" {
+		return method
+	}
+	return clipboard.Auto
+}
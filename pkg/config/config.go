@@ -1,131 +1,279 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/adrg/xdg"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
 	prompt "github.com/carloscastrojumo/remindme/pkg/prompt"
 	"github.com/carloscastrojumo/remindme/pkg/storage"
-	"github.com/carloscastrojumo/remindme/pkg/storage/mongo"
-	"github.com/carloscastrojumo/remindme/pkg/storage/yaml"
 	"github.com/fatih/color"
 	"github.com/spf13/viper"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 var appDir = xdg.Home + "/.config/remindme"
 
-var config = &storage.Config{}
+// EnvPrefix is the prefix of the environment variables overriding configuration keys,
+// e.g. RMM_STORAGETYPE or RMM_YAML_NAME
+const EnvPrefix = "RMM"
 
-// InitConfig initializes the configuration
-func InitConfig() {
-	viper.AddConfigPath(appDir)
-	viper.SetConfigName("config")
+// ConfigEnv is the environment variable used to select an alternate config file
+const ConfigEnv = "RMM_CONFIG"
 
-	// create new folder if it doesn't exist
-	if _, err := os.Stat(appDir); os.IsNotExist(err) {
-		if os.Mkdir(appDir, 0755) != nil {
-			color.Red("Error while creating config folder: %s", err)
-		}
+// InitConfig reads the configuration file, configFile overrides the default location.
+// A missing configuration file is not an error, see IsConfigured.
+func InitConfig(configFile string) error {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	if configFile == "" {
+		configFile = os.Getenv(ConfigEnv)
+	}
+
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		viper.AddConfigPath(appDir)
+		viper.SetConfigName("config")
 	}
 
 	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; create one
-			fmt.Fprintf(os.Stderr, "Config file not found, creating one")
-			promptConfigFile()
-		} else {
-			// Config file was found but another error was produced
-			fmt.Fprintf(os.Stderr, "Whoops. There was an error while reading your config file '%s'", err)
-			os.Exit(1)
+		var notFound viper.ConfigFileNotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error while reading config file %s: %w", configPath(), err)
+	}
+	return nil
+}
+
+// IsConfigured reports whether a storage type is set, in the config file or the environment
+func IsConfigured() bool {
+	return viper.GetString("storageType") != "" || viper.IsSet("profiles")
+}
+
+// PromptConfigFile creates the config file asking the user for the storage configuration.
+// It fails when not attached to a terminal, use Init instead.
+func PromptConfigFile() error {
+	if !prompt.IsInteractive() {
+		return fmt.Errorf("config file %s not found, create it with 'rmm config init' or set %s_STORAGETYPE", configPath(), EnvPrefix)
+	}
+
+	logger.Info("Config file not found, creating one")
+	return Init(PromptProfile(), false)
+}
+
+// Init writes the storage configuration to the config file, as the default profile.
+// An existing storage configuration is only replaced when force is set.
+func Init(profile Profile, force bool) error {
+	if viper.GetString("storageType") != "" && !force {
+		return fmt.Errorf("config file %s already has a storage configuration, use --force to replace it", configPath())
+	}
+
+	switch profile.StorageType {
+	case "yaml":
+		if profile.Yaml.Name == "" {
+			profile.Yaml.Name = appDir + "/data.yaml"
 		}
+	case "mongo":
+	case "http":
+		if profile.HTTP.URL == "" {
+			return errors.New("the server URL is required for http storage")
+		}
+	default:
+		return fmt.Errorf("storage type %q not supported (mongo, yaml, http)", profile.StorageType)
+	}
+
+	return updateConfigFile(func(settings map[string]interface{}) error {
+		delete(settings, "yaml")
+		delete(settings, "mongo")
+		delete(settings, "http")
+		for key, value := range profileSettings(profile) {
+			settings[key] = value
+		}
+		return nil
+	})
+}
+
+// Get returns the value of a configuration key, including environment overrides
+func Get(key string) interface{} {
+	return viper.Get(key)
+}
+
+// Set sets a configuration key in the config file, the value is parsed as YAML
+// so numbers and booleans keep their type
+func Set(key string, value string) error {
+	var parsed interface{}
+	if err := yamlv3.Unmarshal([]byte(value), &parsed); err != nil || parsed == nil {
+		parsed = value
 	}
+
+	path := strings.Split(strings.ToLower(key), ".")
+	return updateConfigFile(func(settings map[string]interface{}) error {
+		for _, part := range path[:len(path)-1] {
+			child, ok := settings[part].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				settings[part] = child
+			}
+			settings = child
+		}
+		settings[path[len(path)-1]] = parsed
+		return nil
+	})
+}
+
+// Unset removes a configuration key from the config file
+func Unset(key string) error {
+	path := strings.Split(strings.ToLower(key), ".")
+	return updateConfigFile(func(settings map[string]interface{}) error {
+		for _, part := range path[:len(path)-1] {
+			child, ok := settings[part].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("key %q %w", key, errdefs.ErrNotFound)
+			}
+			settings = child
+		}
+		if _, ok := settings[path[len(path)-1]]; !ok {
+			return fmt.Errorf("key %q %w", key, errdefs.ErrNotFound)
+		}
+		delete(settings, path[len(path)-1])
+		return nil
+	})
 }
 
-func promptConfigFile() {
-	storageType := prompt.ForString("What storage type do you want to use? (mongo, yaml) [yaml]")
-	if len(storageType) == 0 {
-		storageType = "yaml"
+// configPath returns the path of the config file in use, or the one that would be created
+func configPath() string {
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		return configFile
 	}
+	return appDir + "/config.yaml"
+}
 
-	viper.Set("storageType", storageType)
+// PromptProfile prompts the user for a storage configuration
+func PromptProfile() Profile {
+	profile := Profile{}
+	profile.StorageType = prompt.ForString("What storage type do you want to use? (mongo, yaml, http) [yaml]")
+	if len(profile.StorageType) == 0 {
+		profile.StorageType = "yaml"
+	}
 
-	switch storageType {
+	switch profile.StorageType {
 	case "mongo":
-		viper.Set("mongo.host", prompt.ForString("Mongo host"))
-		viper.Set("mongo.port", prompt.ForString("Mongo port"))
-		viper.Set("mongo.database", prompt.ForString("Mongo database"))
-		viper.Set("mongo.collection", prompt.ForString("Mongo collection"))
+		profile.Mongo.Host = prompt.ForString("Mongo host")
+		profile.Mongo.Port, _ = strconv.Atoi(prompt.ForString("Mongo port"))
+		profile.Mongo.Database = prompt.ForString("Mongo database")
+		profile.Mongo.Collection = prompt.ForString("Mongo collection")
+	case "http":
+		profile.HTTP.URL = prompt.ForString("Server URL, e.g. http://notes.internal:8080")
+		profile.HTTP.TokenEnv = prompt.ForString("Environment variable holding the server token, if any")
 	case "yaml":
 		dataFilename := prompt.ForString("YAML file name (current directory: " + appDir + ") [data.yaml]")
 		if len(dataFilename) == 0 {
 			dataFilename = "data.yaml"
 		}
-		viper.Set("yaml.name", appDir+"/"+dataFilename)
+		profile.Yaml.Name = appDir + "/" + dataFilename
 	}
 
-	saveConfigFile()
+	return profile
 }
 
-func saveConfigFile() {
-	configDir := xdg.Home + "/.config/remindme"
-	viper.AddConfigPath(configDir)
-	viper.WriteConfigAs(configDir + "/config.yaml")
-}
+// GetNoteService returns a new note service for the given profile
+func GetNoteService(ctx context.Context, profileName string) (*storage.NoteService, error) {
+	profile, err := GetProfile(profileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read profile configuration: %w", err)
+	}
 
-// GetNoteService returns a new note service
-func GetNoteService() *storage.NoteService {
-	config.StorageType = viper.GetString("storageType")
+	config := &storage.Config{StorageType: profile.StorageType}
 
 	switch config.StorageType {
 	case "mongo":
-		color.Blue("Using Mongo storage")
-		var mongoConfig mongo.Config
-		err := viper.UnmarshalKey("mongo", &mongoConfig)
+		logger.Debug("Using Mongo storage")
+		config.StorageConfig = &profile.Mongo
 
-		if err != nil {
-			color.Red("Could not read %s configuration: '%s'", config.StorageType, err)
-			os.Exit(1)
-		}
+	case "yaml":
+		logger.Debug("Using YAML storage")
+		config.StorageConfig = &profile.Yaml
 
-		config.StorageConfig = &mongoConfig
+	case "http":
+		logger.Debug("Using HTTP storage, %s", profile.HTTP.URL)
+		config.StorageConfig = &profile.HTTP
 
-	case "yaml":
-		color.Blue("Using YAML storage")
-		var yamlConfig yaml.Config
-		err := viper.UnmarshalKey("yaml", &yamlConfig)
+	default:
+		return nil, fmt.Errorf("no storage type found for profile %s", profileName)
+	}
 
-		if err != nil {
-			color.Red("Whoops. Could not read %s configuration: '%s'", config.StorageType, err)
-			os.Exit(1)
-		}
+	return initNoteService(ctx, config)
+}
 
-		config.StorageConfig = &yamlConfig
+// GetNoteSources returns a note service for each of the given profiles, initialized concurrently.
+// Profiles that fail to initialize are left out and reported in the error.
+func GetNoteSources(ctx context.Context, profileNames []string) ([]storage.Source, error) {
+	services := make([]*storage.NoteService, len(profileNames))
+	errs := make([]error, len(profileNames))
 
-	default:
-		color.Red("No storage type found")
-		os.Exit(1)
+	var wg sync.WaitGroup
+	for i, name := range profileNames {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			if services[i], errs[i] = GetNoteService(ctx, name); errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %w", name, errs[i])
+			}
+		}(i, name)
 	}
+	wg.Wait()
 
-	return initNoteService(config)
+	sources := []storage.Source{}
+	for i, name := range profileNames {
+		if services[i] != nil {
+			sources = append(sources, storage.Source{Name: name, Service: services[i]})
+		}
+	}
+	return sources, errors.Join(errs...)
 }
 
-func initNoteService(storageConfig *storage.Config) *storage.NoteService {
-	storeService := storage.GetStorage(storageConfig)
-	return storage.NewNoteService(storeService)
+func initNoteService(ctx context.Context, storageConfig *storage.Config) (*storage.NoteService, error) {
+	storeService, err := storage.GetStorage(ctx, storageConfig)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewNoteService(storeService), nil
 }
 
 // GetConfig prints the current configuration to screen
-func GetConfig() {
+func GetConfig(profileName string) error {
 	color.Blue("Configuration file: %s\n", color.GreenString(viper.ConfigFileUsed()))
-	switch config.StorageType {
+
+	name := ResolveProfile(profileName)
+	profile, err := GetProfile(name)
+	if err != nil {
+		return fmt.Errorf("could not read profile configuration: %w", err)
+	}
+
+	color.Blue("Profile: %s\n", color.GreenString(name))
+	color.Blue("Storage type: %s\n", color.GreenString(profile.StorageType))
+	switch profile.StorageType {
 	case "yaml":
-		color.Blue("Data file: %s\n", color.GreenString(config.StorageConfig.(*yaml.Config).Name))
+		color.Blue("Data file: %s\n", color.GreenString(profile.Yaml.Name))
 	case "mongo":
-		color.Blue("Host: %s\n", color.GreenString(config.StorageConfig.(*mongo.Config).Host))
-		color.Blue("Port: %s\n", color.GreenString(strconv.Itoa(config.StorageConfig.(*mongo.Config).Port)))
-		color.Blue("Database: %s\n", color.GreenString(config.StorageConfig.(*mongo.Config).Database))
-		color.Blue("Collection: %s\n", color.GreenString(config.StorageConfig.(*mongo.Config).Collection))
+		color.Blue("Host: %s\n", color.GreenString(profile.Mongo.Host))
+		color.Blue("Port: %s\n", color.GreenString(strconv.Itoa(profile.Mongo.Port)))
+		color.Blue("Database: %s\n", color.GreenString(profile.Mongo.Database))
+		color.Blue("Collection: %s\n", color.GreenString(profile.Mongo.Collection))
+	case "http":
+		color.Blue("Server: %s\n", color.GreenString(profile.HTTP.URL))
 	}
+	return nil
 }
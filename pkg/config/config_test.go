@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/spf13/viper"
+)
+
+// useConfigFile reads a temporary config file with the content
+func useConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	name := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitConfig(name); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestSetGetUnset(t *testing.T) {
+	name := useConfigFile(t, "storageType: yaml\nyaml:\n  name: /tmp/notes.yaml\n")
+
+	if err := Set("yaml.name", "/tmp/other.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set("mongo.port", "27017"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set("yaml.encrypt", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Get("yaml.name"); got != "/tmp/other.yaml" {
+		t.Errorf("Get(yaml.name) = %v", got)
+	}
+	// values keep their type
+	if got := Get("mongo.port"); got != 27017 {
+		t.Errorf("Get(mongo.port) = %#v, want an int", got)
+	}
+	if got := Get("yaml.encrypt"); got != true {
+		t.Errorf("Get(yaml.encrypt) = %#v, want a bool", got)
+	}
+
+	if err := Unset("yaml.encrypt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Get("yaml.encrypt"); got != nil {
+		t.Errorf("Get(yaml.encrypt) after Unset = %v", got)
+	}
+	if err := Unset("yaml.encrypt"); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Unset() of a missing key = %v, want ErrNotFound", err)
+	}
+	if err := Unset("missing.key"); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Unset() of a missing section = %v, want ErrNotFound", err)
+	}
+
+	// the other keys are left alone
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "storagetype: yaml") || !strings.Contains(string(data), "port: 27017") {
+		t.Errorf("config file =\n%s", data)
+	}
+}
+
+func TestSetReplacesKeysWhateverTheirCase(t *testing.T) {
+	name := useConfigFile(t, "storageType: yaml\ncurrentProfile: work\nMongo:\n  Host: old\n")
+
+	if err := Set("storagetype", "mongo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set("mongo.HOST", "db.internal"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unset("CurrentProfile"); err != nil {
+		t.Fatalf("Unset() of a camelCase key = %v", err)
+	}
+
+	if got := Get("storageType"); got != "mongo" {
+		t.Errorf("Get(storageType) = %v, want mongo", got)
+	}
+	if got := Get("mongo.host"); got != "db.internal" {
+		t.Errorf("Get(mongo.host) = %v", got)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "mongo:\n    host: db.internal\nstoragetype: mongo\n"
+	if string(data) != want {
+		t.Errorf("config file =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestEnvironmentOverrides(t *testing.T) {
+	useConfigFile(t, "storageType: yaml\nyaml:\n  name: /tmp/notes.yaml\n")
+	t.Setenv("RMM_STORAGETYPE", "http")
+	t.Setenv("RMM_YAML_NAME", "/tmp/env.yaml")
+
+	if got := Get("storageType"); got != "http" {
+		t.Errorf("Get(storageType) = %v, want the environment", got)
+	}
+	if got := Get("yaml.name"); got != "/tmp/env.yaml" {
+		t.Errorf("Get(yaml.name) = %v, want the environment", got)
+	}
+
+	// the config file keeps its value
+	if err := Set("storageType", "mongo"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Get("storageType"); got != "http" {
+		t.Errorf("Get(storageType) after Set = %v, want the environment", got)
+	}
+	os.Unsetenv("RMM_STORAGETYPE")
+	if got := Get("storageType"); got != "mongo" {
+		t.Errorf("Get(storageType) without the environment = %v, want mongo", got)
+	}
+}
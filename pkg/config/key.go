@@ -0,0 +1,154 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/spf13/viper"
+	"github.com/zalando/go-keyring"
+)
+
+// DefaultKeyEnv is the environment variable holding the encryption key when encryption.keyEnv isn't set
+const DefaultKeyEnv = EnvPrefix + "_KEY"
+
+// keyringService and keyringUser name the encryption key in the OS keyring
+const (
+	keyringService = "remindme"
+	keyringUser    = "encryption-key"
+)
+
+// ciphers are shared by the profiles using the same key, so it's derived once
+var (
+	ciphersMu sync.Mutex
+	ciphers   = make(map[string]*crypt.Cipher)
+)
+
+// KeyEnv returns the environment variable holding the encryption key
+func KeyEnv() string {
+	if env := viper.GetString("encryption.keyEnv"); env != "" {
+		return env
+	}
+	return DefaultKeyEnv
+}
+
+// KeyFile returns the file holding the encryption key
+func KeyFile() string {
+	if file := viper.GetString("encryption.keyFile"); file != "" {
+		return file
+	}
+	return filepath.Join(appDir, "key")
+}
+
+// UseKeyring reports whether the encryption key is kept in the OS keyring instead of the key file,
+// the macOS Keychain, the Secret Service on Linux or the Windows Credential Manager
+func UseKeyring() bool {
+	return viper.GetBool("encryption.keyring")
+}
+
+// KeyLocation describes where the encryption key is read from, for messages
+func KeyLocation() string {
+	if UseKeyring() {
+		return "the OS keyring"
+	}
+	return KeyFile()
+}
+
+// Cipher returns the cipher of the encryption key, read from the key environment variable,
+// the OS keyring or the key file.
+// It's nil when no key is configured.
+func Cipher() (*crypt.Cipher, error) {
+	key, err := readKey()
+	if err != nil || key == "" {
+		return nil, err
+	}
+
+	ciphersMu.Lock()
+	defer ciphersMu.Unlock()
+	if cipher, ok := ciphers[key]; ok {
+		return cipher, nil
+	}
+	cipher := crypt.New(key)
+	ciphers[key] = cipher
+	return cipher, nil
+}
+
+func readKey() (string, error) {
+	if key := os.Getenv(KeyEnv()); key != "" {
+		return key, nil
+	}
+
+	if UseKeyring() {
+		key, err := keyring.Get(keyringService, keyringUser)
+		if errors.Is(err, keyring.ErrNotFound) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not read the encryption key from the OS keyring: %w", err)
+		}
+		return strings.TrimSpace(key), nil
+	}
+
+	file := KeyFile()
+	info, err := os.Stat(file)
+	if errors.Is(err, os.ErrNotExist) && !viper.IsSet("encryption.keyFile") {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read the encryption key: %w", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("the encryption key file %s can be read by other users, run 'chmod 600 %s'", file, file)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("could not read the encryption key: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("the encryption key file %s is empty", file)
+	}
+	return key, nil
+}
+
+// GenerateKey writes a new random encryption key to the key file or the OS keyring, an existing key is
+// only replaced when forced. It returns where the key was written.
+func GenerateKey(force bool) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	key := base64.StdEncoding.EncodeToString(random)
+
+	if UseKeyring() {
+		if _, err := keyring.Get(keyringService, keyringUser); err == nil && !force {
+			return "", errors.New("the OS keyring already holds a key, notes encrypted with it can't be read with a new key")
+		}
+		if err := keyring.Set(keyringService, keyringUser, key); err != nil {
+			return "", fmt.Errorf("could not write the encryption key to the OS keyring: %w", err)
+		}
+		return KeyLocation(), nil
+	}
+
+	file := KeyFile()
+	if _, err := os.Stat(file); err == nil && !force {
+		return "", fmt.Errorf("the key file %s already exists, notes encrypted with it can't be read with a new key", file)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, []byte(key+"\n"), 0600); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the permissions of an existing file
+	return file, os.Chmod(file, 0600)
+}
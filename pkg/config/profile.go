@@ -0,0 +1,313 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/storage/mongo"
+	"github.com/carloscastrojumo/remindme/pkg/storage/remote"
+	"github.com/carloscastrojumo/remindme/pkg/storage/yaml"
+	"github.com/spf13/viper"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// DefaultProfile is the name of the profile built from the top level storage settings
+const DefaultProfile = "default"
+
+// ProfileEnv is the environment variable used to select a profile
+const ProfileEnv = "RMM_PROFILE"
+
+// Profile is a named storage configuration
+type Profile struct {
+	StorageType string        `mapstructure:"storageType"`
+	Yaml        yaml.Config   `mapstructure:"yaml"`
+	Mongo       mongo.Config  `mapstructure:"mongo"`
+	HTTP        remote.Config `mapstructure:"http"`
+}
+
+// ProfileNames returns the names of all configured profiles, sorted
+func ProfileNames() []string {
+	names := []string{}
+	if viper.GetString("storageType") != "" {
+		names = append(names, DefaultProfile)
+	}
+	for name := range viper.GetStringMap("profiles") {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveProfile returns the profile to use, in order of precedence:
+// the given flag value, RMM_PROFILE, the currentProfile setting and the default profile
+func ResolveProfile(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	if env := os.Getenv(ProfileEnv); env != "" {
+		return env
+	}
+	if current := viper.GetString("currentProfile"); current != "" {
+		return current
+	}
+	return DefaultProfile
+}
+
+// GetProfile returns the profile with the given name
+func GetProfile(name string) (*Profile, error) {
+	// viper keys are case insensitive
+	name = strings.ToLower(name)
+
+	prefix := ""
+	if name != DefaultProfile || viper.IsSet("profiles."+DefaultProfile) {
+		prefix = "profiles." + name + "."
+		if !viper.IsSet(prefix + "storageType") {
+			return nil, fmt.Errorf("profile %q %w", name, errdefs.ErrNotFound)
+		}
+	}
+
+	// keys are read one by one, unlike UnmarshalKey this honours environment overrides
+	profile := Profile{
+		StorageType: viper.GetString(prefix + "storageType"),
+		Yaml:        readYamlConfig(prefix + "yaml."),
+		Mongo:       readMongoConfig(prefix + "mongo."),
+		HTTP:        readHTTPConfig(prefix+"http.", name),
+	}
+
+	if profile.StorageType == "" {
+		return nil, fmt.Errorf("profile %q has no storage type", name)
+	}
+
+	cipher, err := Cipher()
+	if err != nil {
+		return nil, err
+	}
+	profile.Yaml.Cipher = cipher
+	profile.Mongo.Cipher = cipher
+	return &profile, nil
+}
+
+// UseProfile sets the profile used when none is given
+func UseProfile(name string) error {
+	if _, err := GetProfile(name); err != nil {
+		return err
+	}
+	return updateConfigFile(func(settings map[string]interface{}) error {
+		settings["currentprofile"] = strings.ToLower(name)
+		return nil
+	})
+}
+
+// AddProfile adds or replaces a named profile
+func AddProfile(name string, profile Profile) error {
+	name = strings.ToLower(name)
+	if name == "" {
+		return errors.New("profile name is required")
+	}
+	return updateConfigFile(func(settings map[string]interface{}) error {
+		profiles, _ := settings["profiles"].(map[string]interface{})
+		if profiles == nil {
+			profiles = map[string]interface{}{}
+		}
+		profiles[name] = profileSettings(profile)
+		settings["profiles"] = profiles
+		return nil
+	})
+}
+
+// RemoveProfile removes a named profile
+func RemoveProfile(name string) error {
+	name = strings.ToLower(name)
+	return updateConfigFile(func(settings map[string]interface{}) error {
+		profiles, _ := settings["profiles"].(map[string]interface{})
+		if _, ok := profiles[name]; !ok {
+			if name == DefaultProfile {
+				return errors.New("the default profile is defined by the top level settings and cannot be removed")
+			}
+			return fmt.Errorf("profile %q %w", name, errdefs.ErrNotFound)
+		}
+		delete(profiles, name)
+		if len(profiles) == 0 {
+			delete(settings, "profiles")
+		}
+		if current, _ := settings["currentprofile"].(string); current == name {
+			delete(settings, "currentprofile")
+		}
+		return nil
+	})
+}
+
+func readYamlConfig(prefix string) yaml.Config {
+	config := yaml.Config{
+		Name:         viper.GetString(prefix + "name"),
+		Backups:      yaml.DefaultBackups,
+		BackupMaxAge: viper.GetDuration(prefix + "backupMaxAge"),
+		SyncRemote:   viper.GetString(prefix + "sync.remote"),
+		SyncBranch:   viper.GetString(prefix + "sync.branch"),
+		Encrypt:      viper.GetBool(prefix + "encrypt"),
+	}
+	if viper.IsSet(prefix + "backups") {
+		config.Backups = viper.GetInt(prefix + "backups")
+	}
+	return config
+}
+
+func readMongoConfig(prefix string) mongo.Config {
+	return mongo.Config{
+		URI:                   viper.GetString(prefix + "uri"),
+		Host:                  viper.GetString(prefix + "host"),
+		Port:                  viper.GetInt(prefix + "port"),
+		Database:              viper.GetString(prefix + "database"),
+		Collection:            viper.GetString(prefix + "collection"),
+		Username:              viper.GetString(prefix + "username"),
+		PasswordEnv:           viper.GetString(prefix + "passwordEnv"),
+		PasswordFile:          viper.GetString(prefix + "passwordFile"),
+		AuthSource:            viper.GetString(prefix + "authSource"),
+		AuthMechanism:         viper.GetString(prefix + "authMechanism"),
+		ReplicaSet:            viper.GetString(prefix + "replicaSet"),
+		TLS:                   viper.GetBool(prefix + "tls"),
+		TLSCAFile:             viper.GetString(prefix + "tlsCAFile"),
+		TLSCertificateKeyFile: viper.GetString(prefix + "tlsCertificateKeyFile"),
+		TLSInsecure:           viper.GetBool(prefix + "tlsInsecure"),
+		ConnectTimeout:        viper.GetDuration(prefix + "connectTimeout"),
+		Timeout:               viper.GetDuration(prefix + "timeout"),
+		AutoMigrate:           viper.GetBool(prefix + "autoMigrate"),
+	}
+}
+
+func readHTTPConfig(prefix string, profile string) remote.Config {
+	config := remote.Config{
+		URL:       viper.GetString(prefix + "url"),
+		TokenEnv:  viper.GetString(prefix + "tokenEnv"),
+		TokenFile: viper.GetString(prefix + "tokenFile"),
+		Timeout:   viper.GetDuration(prefix + "timeout"),
+		Retries:   remote.DefaultRetries,
+	}
+	if viper.IsSet(prefix + "retries") {
+		config.Retries = viper.GetInt(prefix + "retries")
+	}
+	if !viper.IsSet(prefix+"cache") || viper.GetBool(prefix+"cache") {
+		config.Cache = filepath.Join(xdg.CacheHome, "remindme", profile+".json")
+	}
+	return config
+}
+
+func profileSettings(profile Profile) map[string]interface{} {
+	settings := map[string]interface{}{"storagetype": profile.StorageType}
+	switch profile.StorageType {
+	case "yaml":
+		settings["yaml"] = map[string]interface{}{"name": profile.Yaml.Name}
+	case "mongo":
+		mongoSettings := map[string]interface{}{
+			"database":   profile.Mongo.Database,
+			"collection": profile.Mongo.Collection,
+		}
+		if profile.Mongo.URI != "" {
+			mongoSettings["uri"] = profile.Mongo.URI
+		} else {
+			mongoSettings["host"] = profile.Mongo.Host
+			mongoSettings["port"] = profile.Mongo.Port
+		}
+
+		// optional settings are only written when given
+		optional := map[string]string{
+			"username":              profile.Mongo.Username,
+			"passwordenv":           profile.Mongo.PasswordEnv,
+			"passwordfile":          profile.Mongo.PasswordFile,
+			"authsource":            profile.Mongo.AuthSource,
+			"authmechanism":         profile.Mongo.AuthMechanism,
+			"replicaset":            profile.Mongo.ReplicaSet,
+			"tlscafile":             profile.Mongo.TLSCAFile,
+			"tlscertificatekeyfile": profile.Mongo.TLSCertificateKeyFile,
+		}
+		for key, value := range optional {
+			if value != "" {
+				mongoSettings[key] = value
+			}
+		}
+		if profile.Mongo.TLS {
+			mongoSettings["tls"] = true
+		}
+		if profile.Mongo.TLSInsecure {
+			mongoSettings["tlsinsecure"] = true
+		}
+		if profile.Mongo.ConnectTimeout > 0 {
+			mongoSettings["connecttimeout"] = profile.Mongo.ConnectTimeout.String()
+		}
+		if profile.Mongo.Timeout > 0 {
+			mongoSettings["timeout"] = profile.Mongo.Timeout.String()
+		}
+		settings["mongo"] = mongoSettings
+	case "http":
+		httpSettings := map[string]interface{}{"url": profile.HTTP.URL}
+		if profile.HTTP.TokenEnv != "" {
+			httpSettings["tokenenv"] = profile.HTTP.TokenEnv
+		}
+		if profile.HTTP.TokenFile != "" {
+			httpSettings["tokenfile"] = profile.HTTP.TokenFile
+		}
+		settings["http"] = httpSettings
+	}
+	return settings
+}
+
+// updateConfigFile edits the config file on disk and reloads it.
+// The file is edited directly because viper has no way of unsetting keys,
+// keys are kept in lower case as viper writes them, e.g. storageType written by hand is replaced by storagetype.
+func updateConfigFile(update func(settings map[string]interface{}) error) error {
+	configFile := configPath()
+
+	settings := map[string]interface{}{}
+	if data, err := os.ReadFile(configFile); err == nil {
+		if err := yamlv3.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("error while reading config file: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	settings = lowerKeys(settings)
+	if err := update(settings); err != nil {
+		return err
+	}
+
+	data, err := yamlv3.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("error while writing config file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		return fmt.Errorf("error while creating config folder: %w", err)
+	}
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		return fmt.Errorf("error while writing config file: %w", err)
+	}
+
+	viper.SetConfigFile(configFile)
+	return viper.ReadInConfig()
+}
+
+// lowerKeys returns the settings with their keys in lower case, as viper reads them.
+// A key already in lower case wins over the same key written otherwise.
+func lowerKeys(settings map[string]interface{}) map[string]interface{} {
+	lowered := make(map[string]interface{}, len(settings))
+	for _, lower := range []bool{false, true} {
+		for key, value := range settings {
+			if (key == strings.ToLower(key)) != lower {
+				continue
+			}
+			if child, ok := value.(map[string]interface{}); ok {
+				value = lowerKeys(child)
+			}
+			lowered[strings.ToLower(key)] = value
+		}
+	}
+	return lowered
+}
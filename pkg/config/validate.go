@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/clipboard"
+	"github.com/spf13/viper"
+)
+
+// Issue is a problem found in the configuration
+type Issue struct {
+	Key     string
+	Problem string
+	Fix     string
+}
+
+// storageKeys are the keys allowed for a storage configuration, top level or inside a profile
+var storageKeys = []string{
+	"storagetype",
+	"yaml.name",
+	"yaml.backups",
+	"yaml.backupmaxage",
+	"yaml.sync.remote",
+	"yaml.sync.branch",
+	"yaml.encrypt",
+	"mongo.uri",
+	"mongo.host",
+	"mongo.port",
+	"mongo.database",
+	"mongo.collection",
+	"mongo.username",
+	"mongo.passwordenv",
+	"mongo.passwordfile",
+	"mongo.authsource",
+	"mongo.authmechanism",
+	"mongo.replicaset",
+	"mongo.tls",
+	"mongo.tlscafile",
+	"mongo.tlscertificatekeyfile",
+	"mongo.tlsinsecure",
+	"mongo.connecttimeout",
+	"mongo.timeout",
+	"mongo.automigrate",
+	"http.url",
+	"http.tokenenv",
+	"http.tokenfile",
+	"http.timeout",
+	"http.retries",
+	"http.cache",
+}
+
+// globalKeys are the keys only allowed at the top level
+var globalKeys = []string{
+	"currentprofile",
+	"encryption.keyenv",
+	"encryption.keyfile",
+	"encryption.keyring",
+	"clipboard.copy",
+	"clipboard.method",
+}
+
+// Validate checks the configuration against the known keys and the requirements of each storage type
+func Validate() []Issue {
+	issues := []Issue{}
+
+	if !IsConfigured() {
+		return append(issues, Issue{
+			Problem: "no storage configured",
+			Fix:     "run 'rmm config init --storage yaml' or set " + EnvPrefix + "_STORAGETYPE",
+		})
+	}
+
+	for _, key := range viper.AllKeys() {
+		if !isKnownKey(key) {
+			issues = append(issues, Issue{
+				Key:     key,
+				Problem: "unknown key",
+				Fix:     fmt.Sprintf("check for typos or run 'rmm config unset %s'", key),
+			})
+		}
+	}
+
+	for _, name := range ProfileNames() {
+		issues = append(issues, validateProfile(name)...)
+	}
+
+	if current := viper.GetString("currentProfile"); current != "" {
+		if _, err := GetProfile(current); err != nil {
+			issues = append(issues, Issue{
+				Key:     "currentprofile",
+				Problem: fmt.Sprintf("profile %q does not exist", current),
+				Fix:     "run 'rmm profile use <profile>' with one of: " + strings.Join(ProfileNames(), ", "),
+			})
+		}
+	}
+
+	if method := ClipboardMethod(); !contains(clipboard.Methods, method) {
+		issues = append(issues, Issue{
+			Key:     "clipboard.method",
+			Problem: fmt.Sprintf("clipboard method %q not supported", method),
+			Fix:     "run 'rmm config set clipboard.method <method>' with one of: " + strings.Join(clipboard.Methods, ", "),
+		})
+	}
+
+	return issues
+}
+
+func validateProfile(name string) []Issue {
+	issues := []Issue{}
+	prefix := ""
+	if name != DefaultProfile || viper.IsSet("profiles."+DefaultProfile) {
+		prefix = "profiles." + name + "."
+	}
+
+	profile, err := GetProfile(name)
+	if err != nil {
+		return append(issues, Issue{Key: prefix + "storagetype", Problem: err.Error(), Fix: "set the storage type to yaml, mongo or http"})
+	}
+
+	required := func(key string, value string) {
+		if value == "" {
+			issues = append(issues, Issue{
+				Key:     prefix + key,
+				Problem: "is required for " + profile.StorageType + " storage",
+				Fix:     fmt.Sprintf("run 'rmm config set %s <value>'", prefix+key),
+			})
+		}
+	}
+
+	switch profile.StorageType {
+	case "yaml":
+		required("yaml.name", profile.Yaml.Name)
+	case "mongo":
+		if profile.Mongo.URI == "" {
+			required("mongo.host", profile.Mongo.Host)
+		}
+		required("mongo.database", profile.Mongo.Database)
+		required("mongo.collection", profile.Mongo.Collection)
+		if profile.Mongo.Username != "" && profile.Mongo.PasswordEnv == "" && profile.Mongo.PasswordFile == "" {
+			issues = append(issues, Issue{
+				Key:     prefix + "mongo.username",
+				Problem: "no password source given",
+				Fix:     fmt.Sprintf("run 'rmm config set %smongo.passwordEnv <VARIABLE>' or set mongo.passwordFile", prefix),
+			})
+		}
+		if profile.Mongo.URI == "" && (profile.Mongo.Port <= 0 || profile.Mongo.Port > 65535) {
+			issues = append(issues, Issue{
+				Key:     prefix + "mongo.port",
+				Problem: fmt.Sprintf("invalid port %d", profile.Mongo.Port),
+				Fix:     fmt.Sprintf("run 'rmm config set %smongo.port 27017'", prefix),
+			})
+		}
+	case "http":
+		required("http.url", profile.HTTP.URL)
+	default:
+		issues = append(issues, Issue{
+			Key:     prefix + "storagetype",
+			Problem: fmt.Sprintf("storage type %q not supported", profile.StorageType),
+			Fix:     fmt.Sprintf("run 'rmm config set %sstoragetype yaml', mongo or http", prefix),
+		})
+	}
+
+	return issues
+}
+
+func isKnownKey(key string) bool {
+	if contains(globalKeys, key) {
+		return true
+	}
+
+	if strings.HasPrefix(key, "profiles.") {
+		// profiles.<name>.<storage key>
+		parts := strings.SplitN(key, ".", 3)
+		if len(parts) < 3 {
+			return false
+		}
+		key = parts[2]
+	}
+
+	return contains(storageKeys, key)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Versions of the encrypted data. Version 1 derives the key from a passphrase and a salt, version 2 uses
+// a random key, as written by 'rmm config keygen', as it is.
+const (
+	versionPassphrase = 1
+	versionKey        = 2
+)
+
+// fieldPrefix marks an encrypted field value, e.g. the command of a secret note, followed by its version
+const fieldPrefix = "enc:v"
+
+// fileHeader starts an encrypted data file, followed by its version and a new line
+const fileHeader = "RMM-ENCRYPTED-V"
+
+const saltSize = 16
+
+// scrypt parameters, about 130ms to derive a key
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrNoKey is returned when encrypted data is read or written without a key
+var ErrNoKey = errors.New("no encryption key, set it in $RMM_KEY, in the key file or in the keyring")
+
+// ErrWrongKey is returned when the data wasn't encrypted with the key or was modified
+var ErrWrongKey = errors.New("cannot decrypt, the key is wrong or the data is corrupted")
+
+// Cipher encrypts data with a key. A random key of 32 bytes in base64 is used as it is, other
+// keys are passphrases the key is derived from. A passphrase is derived once: every encryption
+// uses the salt of the first data decrypted, or a new salt when none was, and a new nonce.
+type Cipher struct {
+	passphrase []byte
+	// key is the random key, nil for passphrases
+	key []byte
+
+	mu    sync.Mutex
+	salt  []byte
+	aeads map[string]cipher.AEAD
+}
+
+// New returns a cipher for the key
+func New(key string) *Cipher {
+	c := &Cipher{passphrase: []byte(key), aeads: make(map[string]cipher.AEAD)}
+	if raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key)); err == nil && len(raw) == chacha20poly1305.KeySize {
+		c.key = raw
+	}
+	return c
+}
+
+func (c *Cipher) version() int {
+	if c.key != nil {
+		return versionKey
+	}
+	return versionPassphrase
+}
+
+// aead returns the AEAD of a version, passphrase keys are derived for the salt and cached
+func (c *Cipher) aead(version int, salt []byte) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := fmt.Sprintf("%d:%s", version, salt)
+	if aead, ok := c.aeads[id]; ok {
+		return aead, nil
+	}
+
+	key := c.key
+	switch version {
+	case versionKey:
+		if key == nil {
+			return nil, ErrWrongKey
+		}
+	case versionPassphrase:
+		var err error
+		if key, err = scrypt.Key(c.passphrase, salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown encryption version %d", version)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	c.aeads[id] = aead
+	return aead, nil
+}
+
+// sealingSalt returns the salt passphrases are derived with, the first one seen is kept
+func (c *Cipher) sealingSalt(seen []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.salt == nil && seen != nil {
+		c.salt = append([]byte{}, seen...)
+	}
+	if c.salt == nil {
+		c.salt = make([]byte, saltSize)
+		if _, err := rand.Read(c.salt); err != nil {
+			c.salt = nil
+			return nil, err
+		}
+	}
+	return c.salt, nil
+}
+
+// seal encrypts the plaintext with the version of the cipher, the result holds the salt of
+// passphrases, the nonce and the ciphertext
+func (c *Cipher) seal(plaintext []byte) (int, []byte, error) {
+	if c == nil {
+		return 0, nil, ErrNoKey
+	}
+
+	version := c.version()
+	var salt []byte
+	if version == versionPassphrase {
+		var err error
+		if salt, err = c.sealingSalt(nil); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	aead, err := c.aead(version, salt)
+	if err != nil {
+		return 0, nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, nil, err
+	}
+
+	sealed := append(append([]byte{}, salt...), nonce...)
+	return version, aead.Seal(sealed, nonce, plaintext, nil), nil
+}
+
+// open decrypts data encrypted by seal with the version
+func (c *Cipher) open(version int, sealed []byte) ([]byte, error) {
+	if c == nil {
+		return nil, ErrNoKey
+	}
+
+	var salt []byte
+	if version == versionPassphrase {
+		if len(sealed) < saltSize {
+			return nil, ErrWrongKey
+		}
+		salt, sealed = sealed[:saltSize], sealed[saltSize:]
+		c.sealingSalt(salt)
+	}
+
+	aead, err := c.aead(version, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+chacha20poly1305.Overhead {
+		return nil, ErrWrongKey
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
+
+// IsEncryptedField reports whether a field value was encrypted by EncryptField
+func IsEncryptedField(value string) bool {
+	return strings.HasPrefix(value, fieldPrefix)
+}
+
+// EncryptField encrypts a field value into printable text
+func (c *Cipher) EncryptField(value string) (string, error) {
+	version, sealed, err := c.seal([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d:%s", fieldPrefix, version, base64.RawStdEncoding.EncodeToString(sealed)), nil
+}
+
+// DecryptField decrypts a field value encrypted by EncryptField
+func (c *Cipher) DecryptField(value string) (string, error) {
+	version, sealed, err := parseField(value)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := c.open(version, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// IsCurrentField reports whether a field value is encrypted as EncryptField would encrypt it now,
+// with the same version and salt. Older values are encrypted again when they're saved.
+func (c *Cipher) IsCurrentField(value string) bool {
+	version, sealed, err := parseField(value)
+	if err != nil || c == nil || version != c.version() {
+		return false
+	}
+	if version != versionPassphrase || len(sealed) < saltSize {
+		return true
+	}
+	salt, err := c.sealingSalt(sealed[:saltSize])
+	return err == nil && bytes.Equal(salt, sealed[:saltSize])
+}
+
+func parseField(value string) (int, []byte, error) {
+	versionText, encoded, found := strings.Cut(strings.TrimPrefix(value, fieldPrefix), ":")
+	version, err := strconv.Atoi(versionText)
+	if err != nil || !found {
+		return 0, nil, errors.New("invalid encrypted value")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	return version, sealed, nil
+}
+
+// IsEncryptedFile reports whether the file content was encrypted by EncryptFile
+func IsEncryptedFile(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(fileHeader))
+}
+
+// EncryptFile encrypts the content of a file
+func (c *Cipher) EncryptFile(data []byte) ([]byte, error) {
+	version, sealed, err := c.seal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(fmt.Sprintf("%s%d\n", fileHeader, version)), sealed...), nil
+}
+
+// DecryptFile decrypts the content of a file encrypted by EncryptFile
+func (c *Cipher) DecryptFile(data []byte) ([]byte, error) {
+	header, sealed, found := bytes.Cut(bytes.TrimPrefix(data, []byte(fileHeader)), []byte("\n"))
+	version, err := strconv.Atoi(string(header))
+	if err != nil || !found {
+		return nil, errors.New("invalid encrypted file header")
+	}
+	return c.open(version, sealed)
+}
@@ -0,0 +1,79 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"strings"
+	"testing"
+)
+
+const randomKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func TestFields(t *testing.T) {
+	// notes encrypted by older versions derived every key from the passphrase with a new salt
+	legacy := &Cipher{passphrase: []byte(randomKey), aeads: make(map[string]cipher.AEAD)}
+	old, err := legacy.EncryptField("psql -p old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		prefix string
+	}{
+		{"random key", randomKey, "enc:v2:"},
+		{"passphrase", "correct horse battery staple", "enc:v1:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.key)
+			values := []string{}
+			for _, command := range []string{"psql -p one", "psql -p two"} {
+				value, err := c.EncryptField(command)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.HasPrefix(value, tt.prefix) || !c.IsCurrentField(value) {
+					t.Errorf("EncryptField() = %q, want a current %s value", value, tt.prefix)
+				}
+				if plain, err := New(tt.key).DecryptField(value); err != nil || plain != command {
+					t.Errorf("DecryptField() = %q, %v, want %q", plain, err, command)
+				}
+				values = append(values, value)
+			}
+
+			// a passphrase is derived once, every value has the same salt
+			if len(c.aeads) != 1 {
+				t.Errorf("got %d keys, want 1", len(c.aeads))
+			}
+
+			if _, err := New("another key").DecryptField(values[0]); err != ErrWrongKey {
+				t.Errorf("DecryptField() with another key = %v, want ErrWrongKey", err)
+			}
+		})
+	}
+
+	c := New(randomKey)
+	if plain, err := c.DecryptField(old); err != nil || plain != "psql -p old" {
+		t.Errorf("DecryptField() of an old value = %q, %v", plain, err)
+	}
+	if c.IsCurrentField(old) {
+		t.Error("an old value is current, it wouldn't be encrypted again")
+	}
+}
+
+func TestFiles(t *testing.T) {
+	for _, key := range []string{randomKey, "correct horse battery staple"} {
+		c := New(key)
+		sealed, err := c.EncryptFile([]byte("- id: \"1\"\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncryptedFile(sealed) {
+			t.Errorf("%q isn't an encrypted file", sealed[:20])
+		}
+		if plain, err := New(key).DecryptFile(sealed); err != nil || string(plain) != "- id: \"1\"\n" {
+			t.Errorf("DecryptFile() = %q, %v", plain, err)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/carloscastrojumo/remindme/pkg/clipboard"
+	"github.com/carloscastrojumo/remindme/pkg/config"
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/carloscastrojumo/remindme/pkg/git"
+	"github.com/carloscastrojumo/remindme/pkg/storage/mongo"
+	"github.com/carloscastrojumo/remindme/pkg/storage/remote"
+	"github.com/carloscastrojumo/remindme/pkg/storage/yaml"
+)
+
+// PingTimeout is the time given to MongoDB to answer a ping
+var PingTimeout = 5 * time.Second
+
+// Result is the outcome of a single check, Problem is empty when the check passed
+type Result struct {
+	Name    string
+	Problem string
+	Fix     string
+}
+
+// OK reports whether the check passed
+func (r Result) OK() bool {
+	return r.Problem == ""
+}
+
+// Run runs every check for the given profiles
+func Run(ctx context.Context, profiles []string) []Result {
+	results := checkConfig()
+	for _, name := range profiles {
+		results = append(results, checkProfile(ctx, name)...)
+	}
+	return append(results, checkClipboard())
+}
+
+func checkConfig() []Result {
+	issues := config.Validate()
+	if len(issues) == 0 {
+		return []Result{{Name: "config file"}}
+	}
+
+	results := []Result{}
+	for _, issue := range issues {
+		name := "config file"
+		if issue.Key != "" {
+			name = "config key " + issue.Key
+		}
+		results = append(results, Result{Name: name, Problem: issue.Problem, Fix: issue.Fix})
+	}
+	return results
+}
+
+func checkProfile(ctx context.Context, name string) []Result {
+	profile, err := config.GetProfile(name)
+	if err != nil {
+		// already reported by the config validation
+		return nil
+	}
+
+	switch profile.StorageType {
+	case "yaml":
+		return checkYaml(name, &profile.Yaml)
+	case "mongo":
+		return checkMongo(ctx, name, &profile.Mongo)
+	case "http":
+		return checkHTTP(ctx, name, &profile.HTTP)
+	}
+	return nil
+}
+
+func checkYaml(profile string, cfg *yaml.Config) []Result {
+	check := fmt.Sprintf("profile %s: data file %s", profile, cfg.Name)
+
+	if _, err := os.Stat(cfg.Name); errors.Is(err, os.ErrNotExist) {
+		dir := filepath.Dir(cfg.Name)
+		if err := writable(dir); err != nil {
+			return []Result{{Name: check, Problem: "does not exist and cannot be created: " + err.Error(), Fix: "create " + dir + " or fix its permissions"}}
+		}
+		return []Result{{Name: check}}
+	}
+
+	keyFix := "set the key the notes were encrypted with in $" + config.KeyEnv() + " or in " + config.KeyLocation()
+	if cfg.Encrypt && cfg.Cipher == nil {
+		return []Result{{Name: check, Problem: "is encrypted but no encryption key is set", Fix: keyFix}}
+	}
+
+	notes, err := yaml.Load(cfg)
+	if errors.Is(err, crypt.ErrNoKey) || errors.Is(err, crypt.ErrWrongKey) {
+		return []Result{{Name: check, Problem: "cannot be decrypted: " + err.Error(), Fix: keyFix}}
+	}
+	if err != nil {
+		return []Result{{Name: check, Problem: "cannot be read: " + err.Error(), Fix: "fix the file permissions or the YAML syntax at the reported line"}}
+	}
+
+	results := []Result{}
+	if f, err := os.OpenFile(cfg.Name, os.O_WRONLY|os.O_APPEND, 0); err != nil {
+		results = append(results, Result{Name: check, Problem: "is not writable: " + err.Error(), Fix: "run 'chmod u+w " + cfg.Name + "'"})
+	} else {
+		f.Close()
+		results = append(results, Result{Name: check})
+	}
+
+	if cfg.SyncRemote != "" && !git.Available() {
+		results = append(results, Result{
+			Name:    fmt.Sprintf("profile %s: sync", profile),
+			Problem: "git is not installed",
+			Fix:     "install git or unset yaml.sync.remote",
+		})
+	}
+
+	entries := []entry{}
+	for _, note := range notes {
+		entries = append(entries, entry{ID: note.ID, Command: note.Command})
+	}
+	return append(results, checkDuplicates(profile, entries)...)
+}
+
+func checkMongo(ctx context.Context, profile string, cfg *mongo.Config) []Result {
+	check := fmt.Sprintf("profile %s: mongo %s:%d", profile, cfg.Host, cfg.Port)
+	if cfg.URI != "" {
+		check = fmt.Sprintf("profile %s: mongo %s", profile, cfg.URI)
+	}
+
+	if cfg.ConnectTimeout <= 0 || cfg.ConnectTimeout > PingTimeout {
+		cfg.ConnectTimeout = PingTimeout
+	}
+
+	store, err := mongo.Initialize(ctx, cfg)
+	if err != nil {
+		return []Result{{Name: check, Problem: "not reachable: " + err.Error(), Fix: "check that MongoDB is running and the address, credentials and TLS settings are correct"}}
+	}
+	defer store.Close(context.Background())
+
+	notes, err := store.GetAll(ctx)
+	if err != nil {
+		return []Result{{Name: check, Problem: "cannot read notes: " + err.Error(), Fix: "check the database and collection names and the user permissions"}}
+	}
+
+	entries := []entry{}
+	for _, note := range notes.([]mongo.Note) {
+		entries = append(entries, entry{ID: note.ID.Hex(), Command: note.Command})
+	}
+	return append([]Result{{Name: check}}, checkDuplicates(profile, entries)...)
+}
+
+func checkHTTP(ctx context.Context, profile string, cfg *remote.Config) []Result {
+	check := fmt.Sprintf("profile %s: server %s", profile, cfg.URL)
+
+	// the server is checked, not the notes cached when it can't be reached
+	cfg.Cache = ""
+	cfg.Retries = 0
+	if cfg.Timeout <= 0 || cfg.Timeout > PingTimeout {
+		cfg.Timeout = PingTimeout
+	}
+
+	store, err := remote.Initialize(cfg)
+	if err != nil {
+		return []Result{{Name: check, Problem: err.Error(), Fix: "check http.url and the token settings"}}
+	}
+	defer store.Close(context.Background())
+
+	notes, err := store.GetAll(ctx)
+	if err != nil {
+		return []Result{{Name: check, Problem: "cannot read notes: " + err.Error(), Fix: "check that 'rmm serve' is running at that address and the token is the one it was given"}}
+	}
+
+	entries := []entry{}
+	for _, note := range notes.([]remote.Note) {
+		entries = append(entries, entry{ID: note.ID, Command: note.Command})
+	}
+	return append([]Result{{Name: check}}, checkDuplicates(profile, entries)...)
+}
+
+// entry is the part of a note checked for duplicates
+type entry struct {
+	ID      string
+	Command string
+}
+
+func checkDuplicates(profile string, entries []entry) []Result {
+	results := []Result{}
+	ids := make(map[string]int)
+	commands := make(map[string]string)
+
+	for _, e := range entries {
+		ids[e.ID]++
+		if ids[e.ID] == 2 {
+			results = append(results, Result{
+				Name:    fmt.Sprintf("profile %s: note %s", profile, e.ID),
+				Problem: "duplicate ID",
+				Fix:     "edit the data file and give one of the notes a new ID",
+			})
+		}
+
+		if id, ok := commands[e.Command]; ok {
+			results = append(results, Result{
+				Name:    fmt.Sprintf("profile %s: notes %s and %s", profile, id, e.ID),
+				Problem: "duplicate command: " + e.Command,
+				Fix:     "merge them with 'rmm dedupe' or remove one with 'rmm rm --id " + e.ID + "'",
+			})
+		} else {
+			commands[e.Command] = e.ID
+		}
+	}
+
+	if len(results) == 0 {
+		results = append(results, Result{Name: fmt.Sprintf("profile %s: duplicates", profile)})
+	}
+	return results
+}
+
+func checkClipboard() Result {
+	result := Result{Name: "clipboard"}
+	switch clipboard.Resolve(config.ClipboardMethod()) {
+	case clipboard.OSC52:
+		return result
+	case clipboard.Tmux:
+		if _, err := exec.LookPath("tmux"); err != nil {
+			result.Problem = "tmux not found, notes won't be copied"
+			result.Fix = "install tmux or run 'rmm config set clipboard.method auto'"
+		}
+		return result
+	case clipboard.System:
+		if clipboard.SystemAvailable() {
+			return result
+		}
+	case "":
+		// auto found no way of copying
+	default:
+		// an unknown method is reported with the config file
+		return result
+	}
+
+	result.Problem = "no clipboard tool found, notes won't be copied"
+	result.Fix = "install one of: xsel, xclip, wl-clipboard or Termux:API, or run 'rmm config set clipboard.method osc52' to copy through the terminal"
+	return result
+}
+
+// writable checks that files can be created in dir
+func writable(dir string) error {
+	f, err := os.CreateTemp(dir, ".rmm-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
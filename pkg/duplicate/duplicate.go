@@ -0,0 +1,303 @@
+package duplicate
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Kind is how close two commands are
+type Kind int
+
+const (
+	// None commands are different
+	None Kind = iota
+	// FlagOrder commands only differ in the order of their flags, e.g. "ls -a -l" and "ls -l -a"
+	FlagOrder
+	// Spacing commands only differ in whitespace or quoting, e.g. "echo 'a'" and "echo  a"
+	Spacing
+	// Exact commands are identical
+	Exact
+)
+
+// String describes how the commands differ
+func (k Kind) String() string {
+	switch k {
+	case Exact:
+		return "same command"
+	case Spacing:
+		return "same command, except whitespace or quoting"
+	case FlagOrder:
+		return "same command, except the order of its flags"
+	}
+	return "different commands"
+}
+
+// Group is a group of duplicate commands, Kind is the loosest match between them
+type Group struct {
+	Kind    Kind
+	Indexes []int
+}
+
+// Compare returns how close two commands are
+func Compare(a string, b string) Kind {
+	switch {
+	case a == b:
+		return Exact
+	case normalize(a) == normalize(b):
+		return Spacing
+	case sameFlags(split(a), split(b)):
+		return FlagOrder
+	}
+	return None
+}
+
+// Groups returns the groups of duplicate commands, by their position in commands.
+// Groups are in the order of their first command, commands that have no duplicate aren't returned.
+func Groups(commands []string) []Group {
+	// commands are only compared to the others with the same words, whatever their order
+	buckets := make(map[string][]int)
+	all := [][]int{}
+	for i, command := range commands {
+		k := key(split(command))
+		found := false
+		for _, g := range buckets[k] {
+			if Compare(commands[all[g][0]], command) != None {
+				all[g] = append(all[g], i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			buckets[k] = append(buckets[k], len(all))
+			all = append(all, []int{i})
+		}
+	}
+
+	groups := []Group{}
+	for _, indexes := range all {
+		if len(indexes) < 2 {
+			continue
+		}
+		kind := Exact
+		for _, i := range indexes[1:] {
+			kind = min(kind, Compare(commands[indexes[0]], commands[i]))
+		}
+		groups = append(groups, Group{Kind: kind, Indexes: indexes})
+	}
+	return groups
+}
+
+// separators split a command in the commands it runs, e.g. the commands of a pipeline
+var separators = map[string]bool{"|": true, "||": true, "&&": true, ";": true, "&": true, "\n": true}
+
+// normalize is the same for commands that only differ in whitespace or quoting
+func normalize(command string) string {
+	return strings.Join(words(command), "\x00")
+}
+
+// segment is one of the commands run by a command line, e.g. a command of a pipeline
+type segment struct {
+	flags []string
+	// words are the other words in order, the command name first
+	words []string
+	// after is the position in flags of the flag right before each word, which may be its value,
+	// -1 when the word can't be a value
+	after []int
+}
+
+// line is a command line split in its segments and the separators after them
+type line struct {
+	segments []segment
+	seps     []string
+}
+
+func split(command string) line {
+	l := line{}
+	current := []string{}
+	for _, word := range append(words(command), ";") {
+		if !separators[word] {
+			current = append(current, word)
+			continue
+		}
+		if len(current) > 0 {
+			l.segments, l.seps = append(l.segments, parseSegment(current)), append(l.seps, word)
+		}
+		current = current[:0]
+	}
+	return l
+}
+
+func parseSegment(words []string) segment {
+	s := segment{words: []string{words[0]}, after: []int{-1}}
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			for _, w := range words[i:] {
+				s.words, s.after = append(s.words, w), append(s.after, -1)
+			}
+			break
+		}
+		if isFlag(word) {
+			s.flags = append(s.flags, word)
+			continue
+		}
+
+		after := -1
+		if previous := words[i-1]; i > 1 && isFlag(previous) && takesValue(previous) && !isOperator(word) {
+			after = len(s.flags) - 1
+		}
+		s.words, s.after = append(s.words, word), append(s.after, after)
+	}
+	return s
+}
+
+// key is the same for commands that may only differ in whitespace, quoting or the order of their flags:
+// the same separators, and segments with the same flags and words in any order
+func key(l line) string {
+	keys := []string{}
+	for i, s := range l.segments {
+		flags, words := slices.Clone(s.flags), slices.Clone(s.words)
+		sort.Strings(flags)
+		sort.Strings(words)
+		keys = append(keys, strings.Join(words, "\x01")+"\x02"+strings.Join(flags, "\x02"), l.seps[i])
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// sameFlags reports whether the command lines only differ in the order of their flags
+func sameFlags(a line, b line) bool {
+	if !slices.Equal(a.seps, b.seps) {
+		return false
+	}
+	for i := range a.segments {
+		aPaired, bPaired := pairValues(a.segments[i], b.segments[i])
+		if a.segments[i].key(aPaired) != b.segments[i].key(bPaired) {
+			return false
+		}
+	}
+	return true
+}
+
+// pairValues decides which words are the values of the flag before them. A word is the value of
+// its flag only when the same flag is right before it in both segments, e.g. in "rm -r -f dir" and
+// "rm -f -r dir" the directory isn't a value but in "ps -o pid -e" and "ps -e -o pid" pid is.
+// The occurrences of a word are matched in order.
+func pairValues(a segment, b segment) ([]bool, []bool) {
+	aPaired, bPaired := make([]bool, len(a.words)), make([]bool, len(b.words))
+	seen := make(map[string]int)
+	for i, word := range a.words {
+		n := seen[word]
+		seen[word]++
+		j := nth(b.words, word, n)
+		if j < 0 || a.after[i] < 0 || b.after[j] < 0 || a.flags[a.after[i]] != b.flags[b.after[j]] {
+			continue
+		}
+		aPaired[i], bPaired[j] = true, true
+	}
+	return aPaired, bPaired
+}
+
+// nth returns the position of the nth occurrence of the word, -1 when there's none
+func nth(words []string, word string, n int) int {
+	for i, w := range words {
+		if w != word {
+			continue
+		}
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return -1
+}
+
+// key is the same for segments that only differ in the order of their flags, the paired words are
+// kept with their flag and the others are in order
+func (s segment) key(paired []bool) string {
+	flags := slices.Clone(s.flags)
+	positional := []string{}
+	for i, word := range s.words {
+		if paired[i] {
+			flags[s.after[i]] += "\x01" + word
+		} else {
+			positional = append(positional, word)
+		}
+	}
+	sort.Strings(flags)
+	return strings.Join(positional, "\x01") + "\x02" + strings.Join(flags, "\x02")
+}
+
+func isFlag(word string) bool {
+	return len(word) > 1 && word[0] == '-'
+}
+
+// takesValue reports whether the word after the flag may be its value
+func takesValue(flag string) bool {
+	if strings.Contains(flag, "=") {
+		return false
+	}
+	return strings.HasPrefix(flag, "--") || len(flag) == 2
+}
+
+func isOperator(word string) bool {
+	return word != "" && strings.ContainsRune(operators, rune(word[0]))
+}
+
+// operators are the characters of the shell operators, e.g. pipes and redirections
+const operators = "|&;<>()"
+
+// words splits a command in words like a shell would, without the quotes.
+// Operators and newlines are words of their own.
+func words(command string) []string {
+	result := []string{}
+	var word strings.Builder
+	inWord := false
+	end := func() {
+		if inWord {
+			result = append(result, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			end()
+			result = append(result, "\n")
+		case unicode.IsSpace(r):
+			end()
+		case r == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+		case r == '\'' || r == '"':
+			inWord = true
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if r == '"' && runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+		case strings.ContainsRune(operators, r):
+			end()
+			operator := string(r)
+			for i+1 < len(runes) && strings.ContainsRune(operators, runes[i+1]) {
+				i++
+				operator += string(runes[i])
+			}
+			result = append(result, operator)
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	end()
+	return result
+}
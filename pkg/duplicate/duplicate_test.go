@@ -0,0 +1,78 @@
+package duplicate
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want Kind
+	}{
+		{"kubectl get pods", "kubectl get pods", Exact},
+
+		// whitespace and quoting
+		{"kubectl get pods", "kubectl  get   pods", Spacing},
+		{"kubectl get pods", "  kubectl get pods\t", Spacing},
+		{"echo 'hello world'", `echo "hello world"`, Spacing},
+		{"echo 'a'", "echo a", Spacing},
+		{`echo hello\ world`, "echo 'hello world'", Spacing},
+		{"ls | wc -l", "ls|wc -l", Spacing},
+		{"echo 'a b'", "echo a b", None},
+		{"echo 'a|b'", "echo a|b", None},
+
+		// order of the flags
+		{"ls -a -l", "ls -l -a", FlagOrder},
+		{"ls -l -a /tmp", "ls -a -l /tmp", FlagOrder},
+		{"rm -r -f dir", "rm -f -r dir", FlagOrder},
+		{"rm -rf dir", "rm -fr dir", None},
+		{"rm dir -f", "rm -f dir", FlagOrder},
+		{"kubectl get pods -n kube-system -o wide", "kubectl get pods -o wide -n kube-system", FlagOrder},
+		{"kubectl get pods -A -o wide", "kubectl get pods -o wide -A", FlagOrder},
+		{"docker run --rm -it --name web nginx", "docker run -it --name web --rm nginx", FlagOrder},
+		{"git log --oneline --graph --all", "git log --all --graph --oneline", FlagOrder},
+		{"curl -s -H 'Accept: text/plain' example.com", "curl -H 'Accept: text/plain' -s example.com", FlagOrder},
+		{"grep -r --include=*.go foo .", "grep --include=*.go -r foo .", FlagOrder},
+		{"ls -l | grep -v -i foo", "ls -l | grep -i -v foo", FlagOrder},
+
+		// values swapped between flags, positionals in another order, other commands
+		{"kubectl get pods -n wide -o kube-system", "kubectl get pods -n kube-system -o wide", None},
+		{"cp -r a b", "cp -r b a", None},
+		{"cp a b -r", "cp b a -r", None},
+		{"ls -l | grep foo", "grep foo | ls -l", None},
+		{"make build && make test", "make build; make test", None},
+		{"ls -a", "ls -a -a", None},
+		{"rm -- -f dir", "rm -- dir -f", None},
+		{"kubectl get pods", "kubectl get nodes", None},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("Compare() = %s, want %s", got, tt.want)
+			}
+			if got := Compare(tt.b, tt.a); got != tt.want {
+				t.Errorf("Compare() the other way = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroups(t *testing.T) {
+	commands := []string{
+		"rm -r -f dir",
+		"kubectl get pods -n a -o b",
+		"rm -f -r dir",
+		"kubectl get pods -n b -o a",
+		"git status",
+		"rm  -r -f  dir",
+		"kubectl get pods -o b -n a",
+		"kubectl get pods -n b -o a",
+	}
+	want := "[{same command, except the order of its flags [0 2 5]} " +
+		"{same command, except the order of its flags [1 6]} " +
+		"{same command [3 7]}]"
+	if got := fmt.Sprint(Groups(commands)); got != want {
+		t.Errorf("Groups() = %s, want %s", got, want)
+	}
+}
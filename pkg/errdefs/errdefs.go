@@ -0,0 +1,43 @@
+package errdefs
+
+import "errors"
+
+// Errors shared by the storages and the commands, use errors.Is to tell them apart.
+// The storage package re-exports them for its callers.
+var (
+	// ErrNotFound is returned when a note, or another item asked for, doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrAmbiguousID is returned when an ID, or the start of one, matches several notes
+	ErrAmbiguousID = errors.New("ambiguous ID")
+	// ErrStorageUnavailable is returned when the storage can't be reached or read, e.g. MongoDB is down
+	ErrStorageUnavailable = errors.New("storage unavailable")
+	// ErrConflict is returned when changes can't be merged, e.g. a field changed on both sides
+	ErrConflict = errors.New("conflict")
+	// ErrDuplicate is returned when a note is added with the command of another note
+	ErrDuplicate = errors.New("duplicate")
+	// ErrUsage is returned for invalid arguments or flags
+	ErrUsage = errors.New("invalid usage")
+)
+
+// marked is an error marked with the kind of error it is
+type marked struct {
+	err  error
+	kind error
+}
+
+func (m *marked) Error() string {
+	return m.err.Error()
+}
+
+func (m *marked) Unwrap() []error {
+	return []error{m.err, m.kind}
+}
+
+// Mark returns the error marked as a kind of error: errors.Is(err, kind) is true and its message
+// is unchanged, e.g. Mark(err, ErrStorageUnavailable). It returns nil when err is nil.
+func Mark(err error, kind error) error {
+	if err == nil {
+		return nil
+	}
+	return &marked{err: err, kind: kind}
+}
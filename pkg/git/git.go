@@ -0,0 +1,133 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrNotFound is returned when a file or revision doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// defaultIdentity is used to commit when git has no user configured, e.g. in containers
+var defaultIdentity = []string{"-c", "user.name=remindme", "-c", "user.email=remindme@localhost"}
+
+// Repo is a git working tree, Dir can be any directory inside it
+type Repo struct {
+	Dir string
+}
+
+// Available reports whether the git command is installed
+func Available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// Run runs a git command in the repository and returns its output without the trailing newline
+func (r *Repo) Run(ctx context.Context, args ...string) (string, error) {
+	out, err := r.output(ctx, args...)
+	return strings.TrimRight(string(out), "\n"), err
+}
+
+func (r *Repo) output(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", r.Dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", subcommand(args), message)
+	}
+	return stdout.Bytes(), nil
+}
+
+// subcommand returns the git command run by the arguments, skipping the configuration options
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
+
+// Succeeds runs a git command that answers a question with its exit status
+func (r *Repo) Succeeds(ctx context.Context, args ...string) bool {
+	_, err := r.Run(ctx, args...)
+	return err == nil
+}
+
+// IsRepo reports whether the directory is inside a git working tree
+func (r *Repo) IsRepo(ctx context.Context) bool {
+	return r.Succeeds(ctx, "rev-parse", "--is-inside-work-tree")
+}
+
+// Init creates a repository in the directory with the given initial branch
+func (r *Repo) Init(ctx context.Context, branch string) error {
+	_, err := r.Run(ctx, "init", "--initial-branch", branch)
+	return err
+}
+
+// Commit commits the changes to the given paths only, other changes in the working tree are left alone.
+// It returns false when there was nothing to commit.
+func (r *Repo) Commit(ctx context.Context, message string, paths ...string) (bool, error) {
+	if _, err := r.Run(ctx, append([]string{"add", "--"}, paths...)...); err != nil {
+		return false, err
+	}
+
+	// diff exits with 1 when there are staged changes
+	if r.Succeeds(ctx, append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...) {
+		return false, nil
+	}
+
+	args := append(r.identity(ctx), "commit", "--quiet", "-m", message, "--")
+	if _, err := r.Run(ctx, append(args, paths...)...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Merge merges a revision into the current branch. It returns no error when the merge stopped
+// because of conflicts, they must be solved and the merge concluded with CommitMerge.
+func (r *Repo) Merge(ctx context.Context, revision string, options ...string) error {
+	args := append(append(r.identity(ctx), "merge"), options...)
+	_, err := r.Run(ctx, append(args, revision)...)
+	if err != nil && r.Succeeds(ctx, "rev-parse", "--quiet", "--verify", "MERGE_HEAD") {
+		return nil
+	}
+	return err
+}
+
+// CommitMerge concludes a merge in progress
+func (r *Repo) CommitMerge(ctx context.Context, message string) error {
+	_, err := r.Run(ctx, append(r.identity(ctx), "commit", "--quiet", "--no-edit", "-m", message)...)
+	return err
+}
+
+// identity returns the options to commit with the default identity when git has none configured
+func (r *Repo) identity(ctx context.Context) []string {
+	if name, _ := r.Run(ctx, "config", "user.name"); name != "" {
+		return nil
+	}
+	return defaultIdentity
+}
+
+// Show returns a file at a revision, the path is relative to the repository directory.
+// ErrNotFound is returned when the file doesn't exist at the revision.
+func (r *Repo) Show(ctx context.Context, revision string, path string) ([]byte, error) {
+	spec := revision + ":./" + path
+	if !r.Succeeds(ctx, "cat-file", "-e", spec) {
+		return nil, ErrNotFound
+	}
+
+	return r.output(ctx, "show", spec)
+}
@@ -0,0 +1,98 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newRepo(t *testing.T) *Repo {
+	t.Helper()
+	if !Available() {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo := &Repo{Dir: t.TempDir()}
+	if err := repo.Init(context.Background(), "main"); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func write(t *testing.T, repo *Repo, name string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repo.Dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	if !repo.IsRepo(ctx) {
+		t.Fatal("IsRepo() = false after Init")
+	}
+
+	write(t, repo, "notes.yaml", "notes: []\n")
+	write(t, repo, "other", "left alone\n")
+	if committed, err := repo.Commit(ctx, "Add notes", "notes.yaml"); !committed || err != nil {
+		t.Fatalf("Commit() = %t, %v, want committed", committed, err)
+	}
+	if committed, err := repo.Commit(ctx, "Add notes", "notes.yaml"); committed || err != nil {
+		t.Errorf("Commit() without changes = %t, %v, want nothing committed", committed, err)
+	}
+
+	// only the given paths are committed, with the default identity
+	if status, _ := repo.Run(ctx, "status", "--porcelain"); status != "?? other" {
+		t.Errorf("status = %q, want the other file untracked", status)
+	}
+	if author, _ := repo.Run(ctx, "log", "-1", "--format=%an"); author != "remindme" {
+		t.Errorf("author = %q, want the default identity", author)
+	}
+
+	data, err := repo.Show(ctx, "HEAD", "notes.yaml")
+	if err != nil || string(data) != "notes: []\n" {
+		t.Errorf("Show() = %q, %v", data, err)
+	}
+	if _, err := repo.Show(ctx, "HEAD", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Show() of a file not committed = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMergeStopsOnConflicts(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	write(t, repo, "notes.yaml", "base\n")
+	repo.Commit(ctx, "Base", "notes.yaml")
+	repo.Run(ctx, "branch", "other")
+
+	write(t, repo, "notes.yaml", "ours\n")
+	repo.Commit(ctx, "Ours", "notes.yaml")
+	repo.Run(ctx, "checkout", "--quiet", "other")
+	write(t, repo, "notes.yaml", "theirs\n")
+	repo.Commit(ctx, "Theirs", "notes.yaml")
+	repo.Run(ctx, "checkout", "--quiet", "main")
+
+	if err := repo.Merge(ctx, "other", "--quiet", "--no-edit"); err != nil {
+		t.Fatalf("Merge() with conflicts = %v, want the merge in progress", err)
+	}
+	write(t, repo, "notes.yaml", "merged\n")
+	repo.Run(ctx, "add", "notes.yaml")
+	if err := repo.CommitMerge(ctx, "Merge"); err != nil {
+		t.Fatal(err)
+	}
+	if parents, _ := repo.Run(ctx, "rev-list", "--parents", "-n", "1", "HEAD"); len(strings.Fields(parents)) != 3 {
+		t.Errorf("HEAD has parents %q, want a merge commit", parents)
+	}
+
+	// other failures are returned
+	if err := repo.Merge(ctx, "missing", "--quiet"); err == nil || !strings.HasPrefix(err.Error(), "git merge:") {
+		t.Errorf("Merge() of a missing revision = %v, want a git merge error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// Level is how much is logged, each level includes the ones before it
+type Level int
+
+const (
+	// LevelError only logs errors, e.g. with --quiet
+	LevelError Level = iota
+	// LevelWarn logs warnings, e.g. a command that looks like it holds a secret
+	LevelWarn
+	// LevelInfo logs what commands did, e.g. "Note added", it's the default
+	LevelInfo
+	// LevelDebug logs details, e.g. the storage used, with --verbose
+	LevelDebug
+)
+
+var (
+	level            = LevelInfo
+	output io.Writer = os.Stderr
+	// colored is set when the output is a terminal and colors aren't disabled with NO_COLOR
+	colored = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" &&
+		(isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()))
+)
+
+// SetLevel sets how much is logged
+func SetLevel(l Level) {
+	level = l
+}
+
+// DisableColor logs without colors
+func DisableColor() {
+	colored = false
+}
+
+// SetOutput sets where messages are logged, the standard error by default
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// Debug logs details only shown with --verbose
+func Debug(format string, a ...interface{}) {
+	log(LevelDebug, color.FgHiBlack, format, a...)
+}
+
+// Info logs what a command is doing
+func Info(format string, a ...interface{}) {
+	log(LevelInfo, color.FgBlue, format, a...)
+}
+
+// Success logs what a command did, e.g. "Note added"
+func Success(format string, a ...interface{}) {
+	log(LevelInfo, color.FgGreen, format, a...)
+}
+
+// Warn logs a warning
+func Warn(format string, a ...interface{}) {
+	log(LevelWarn, color.FgYellow, format, a...)
+}
+
+// Error logs an error, errors are logged even with --quiet
+func Error(format string, a ...interface{}) {
+	log(LevelError, color.FgRed, format, a...)
+}
+
+func log(l Level, attribute color.Attribute, format string, a ...interface{}) {
+	if l > level {
+		return
+	}
+
+	message := fmt.Sprintf(format, a...)
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+
+	c := color.New(attribute)
+	if colored {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	c.Fprint(output, message)
+}
@@ -0,0 +1,314 @@
+package merge
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Note is a note of a YAML data file. Notes are merged as generic maps so every field
+// is kept, including the ones this version of rmm doesn't know about.
+type Note map[string]interface{}
+
+// ID returns the note ID
+func (n Note) ID() string {
+	return fmt.Sprint(n["id"])
+}
+
+// Command returns the note command
+func (n Note) Command() string {
+	if command, ok := n["command"].(string); ok {
+		return command
+	}
+	return ""
+}
+
+// Conflict is a note whose fields were changed differently on both sides. Base is nil for notes
+// added on both sides with the same ID or the same command.
+type Conflict struct {
+	ID     string
+	Fields []string
+	Base   Note
+	Ours   Note
+	Theirs Note
+	// Merged is the note in the merge result, the conflicting fields hold our value until resolved
+	Merged Note
+}
+
+// Resolve sets the value of a conflicting field in the merge result
+func (c *Conflict) Resolve(field string, value interface{}) {
+	if value == nil {
+		delete(c.Merged, field)
+		return
+	}
+	c.Merged[field] = value
+}
+
+// Result is the outcome of a merge
+type Result struct {
+	Notes     []Note
+	Conflicts []Conflict
+}
+
+// Parse reads the notes of a YAML data file
+func Parse(data []byte) ([]Note, error) {
+	var notes []Note
+	if err := yaml.Unmarshal(data, &notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// fieldOrder is the order of the fields in the data file, other fields follow sorted by name
+var fieldOrder = []string{"id", "tags", "command", "description", "uses", "secret", "shell"}
+
+// Marshal writes the notes as a YAML data file
+func Marshal(notes []Note) ([]byte, error) {
+	if len(notes) == 0 {
+		return []byte{}, nil
+	}
+
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, note := range notes {
+		mapping := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range orderedKeys(note) {
+			var value yaml.Node
+			if err := value.Encode(note[key]); err != nil {
+				return nil, err
+			}
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &value)
+		}
+		list.Content = append(list.Content, mapping)
+	}
+	return yaml.Marshal(list)
+}
+
+func orderedKeys(note Note) []string {
+	keys := []string{}
+	for _, key := range fieldOrder {
+		if _, ok := note[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	others := []string{}
+	for key := range note {
+		if !slices.Contains(fieldOrder, key) {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
+// Files merges three versions of a YAML data file, see Notes
+func Files(base []byte, ours []byte, theirs []byte) ([]byte, *Result, error) {
+	baseNotes, err := Parse(base)
+	if err != nil {
+		return nil, nil, fmt.Errorf("base version: %w", err)
+	}
+	ourNotes, err := Parse(ours)
+	if err != nil {
+		return nil, nil, fmt.Errorf("our version: %w", err)
+	}
+	theirNotes, err := Parse(theirs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("their version: %w", err)
+	}
+
+	result := Notes(baseNotes, ourNotes, theirNotes)
+	data, err := Marshal(result.Notes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, result, nil
+}
+
+// Notes merges two versions of the notes changed from a common base, notes are matched by ID.
+// A note changed on one side only takes that change, a note changed on both sides is merged field
+// by field, a note deleted on one side and changed on the other is kept. Fields changed differently
+// on both sides are conflicts. Tags are merged as sets and the uses counted on both sides are added up.
+// A note they added with the command of one of our notes is merged into it, it keeps our ID.
+func Notes(base []Note, ours []Note, theirs []Note) *Result {
+	baseByID, ourByID, theirByID := byID(base), byID(ours), byID(theirs)
+	result := &Result{}
+	// commands are the positions of our notes in the result by command
+	commands := make(map[string]int)
+
+	add := func(note Note) {
+		result.Notes = append(result.Notes, note)
+	}
+	addOurs := func(note Note) {
+		if _, ok := commands[note.Command()]; !ok {
+			commands[note.Command()] = len(result.Notes)
+		}
+		add(note)
+	}
+
+	for _, our := range ours {
+		id := our.ID()
+		baseNote, inBase := baseByID[id]
+		their, inTheirs := theirByID[id]
+
+		switch {
+		case !inTheirs && inBase && sameContent(our, baseNote):
+			// deleted by them
+		case !inTheirs:
+			// added by us, or changed by us and deleted by them
+			addOurs(our)
+		default:
+			merged, fields := mergeNote(baseNote, our, their)
+			if len(fields) > 0 {
+				result.Conflicts = append(result.Conflicts, Conflict{ID: id, Fields: fields, Base: baseNote, Ours: our, Theirs: their, Merged: merged})
+			}
+			addOurs(merged)
+		}
+	}
+
+	for _, their := range theirs {
+		id := their.ID()
+		if _, inOurs := ourByID[id]; inOurs {
+			continue
+		}
+
+		baseNote, inBase := baseByID[id]
+		position, sameCommand := commands[their.Command()]
+		switch {
+		case inBase && sameContent(their, baseNote):
+			// deleted by us
+		case !inBase && sameCommand:
+			// the same command added on both sides
+			result.mergeInto(position, their)
+		default:
+			// added by them, or changed by them and deleted by us
+			add(their)
+		}
+	}
+
+	return result
+}
+
+// mergeInto merges a note they added into our note with the same command, in place so a conflict
+// already reported for our note keeps resolving the note of the result
+func (r *Result) mergeInto(position int, their Note) {
+	our := r.Notes[position]
+	renamed := maps.Clone(their)
+	renamed["id"] = our["id"]
+
+	merged, fields := mergeNote(Note{}, our, renamed)
+	if len(fields) > 0 {
+		r.Conflicts = append(r.Conflicts, Conflict{ID: our.ID(), Fields: fields, Ours: maps.Clone(our), Theirs: their, Merged: our})
+	}
+	clear(our)
+	maps.Copy(our, merged)
+}
+
+// mergeNote merges the fields of a note changed on both sides, it returns the conflicting fields
+func mergeNote(base Note, ours Note, theirs Note) (Note, []string) {
+	merged := Note{}
+	conflicts := []string{}
+
+	for _, field := range orderedKeys(union(base, ours, theirs)) {
+		b, o, t := base[field], ours[field], theirs[field]
+
+		var value interface{}
+		switch {
+		case field == "uses":
+			continue
+		case field == "tags":
+			value = mergeTags(b, o, t)
+		case reflect.DeepEqual(o, t), reflect.DeepEqual(t, b):
+			value = o
+		case reflect.DeepEqual(o, b):
+			value = t
+		default:
+			value = o
+			conflicts = append(conflicts, field)
+		}
+
+		if value != nil {
+			merged[field] = value
+		}
+	}
+
+	if uses := uses(ours) + uses(theirs) - uses(base); uses > 0 {
+		merged["uses"] = uses
+	}
+	return merged, conflicts
+}
+
+// mergeTags keeps the tags of the base not removed on either side and adds the tags added on both
+func mergeTags(base interface{}, ours interface{}, theirs interface{}) interface{} {
+	b, o, t := toStrings(base), toStrings(ours), toStrings(theirs)
+
+	merged := []interface{}{}
+	seen := make(map[string]bool)
+	for _, tag := range append(append(append([]string{}, b...), o...), t...) {
+		removed := slices.Contains(b, tag) && (!slices.Contains(o, tag) || !slices.Contains(t, tag))
+		if !removed && !seen[tag] {
+			seen[tag] = true
+			merged = append(merged, tag)
+		}
+	}
+
+	if len(merged) == 0 && ours == nil && theirs == nil {
+		return nil
+	}
+	return merged
+}
+
+func toStrings(value interface{}) []string {
+	values, _ := value.([]interface{})
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = fmt.Sprint(v)
+	}
+	return strs
+}
+
+func union(notes ...Note) Note {
+	keys := Note{}
+	for _, note := range notes {
+		for key := range note {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+func byID(notes []Note) map[string]Note {
+	m := make(map[string]Note, len(notes))
+	for _, note := range notes {
+		m[note.ID()] = note
+	}
+	return m
+}
+
+// sameContent compares two notes ignoring their uses
+func sameContent(a Note, b Note) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	return reflect.DeepEqual(withoutUses(a), withoutUses(b))
+}
+
+func withoutUses(note Note) Note {
+	copied := make(Note, len(note))
+	for key, value := range note {
+		if key != "uses" {
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
+func uses(note Note) int {
+	if uses, ok := note["uses"].(int); ok {
+		return uses
+	}
+	return 0
+}
@@ -0,0 +1,279 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, data string) []Note {
+	t.Helper()
+	notes, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notes
+}
+
+const base = `
+- id: "1"
+  tags: [k8s]
+  command: kubectl get pods
+  description: List the pods
+  uses: 2
+- id: "2"
+  tags: [git]
+  command: git status
+`
+
+func TestNotes(t *testing.T) {
+	tests := []struct {
+		name          string
+		ours          string
+		theirs        string
+		want          string
+		wantConflicts string
+	}{
+		{
+			name: "changed on one side",
+			ours: base,
+			theirs: `
+- {id: "1", tags: [k8s], command: kubectl get pods -A, description: List the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+`,
+			want: `
+- {id: "1", tags: [k8s], command: kubectl get pods -A, description: List the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+`,
+		},
+		{
+			name: "different fields changed on both sides",
+			ours: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List all the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+`,
+			theirs: `
+- {id: "1", tags: [k8s], command: kubectl get pods -A, description: List the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+`,
+			want: `
+- {id: "1", tags: [k8s], command: kubectl get pods -A, description: List all the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+`,
+		},
+		{
+			name: "same field changed on both sides",
+			ours: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List all the pods, uses: 2}
+- {id: "2", tags: [git], command: git status, shell: zsh}
+`,
+			theirs: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: Show the pods, uses: 2}
+- {id: "2", tags: [git], command: git status, shell: zsh}
+`,
+			want: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List all the pods, uses: 2}
+- {id: "2", tags: [git], command: git status, shell: zsh}
+`,
+			wantConflicts: "1: [description]",
+		},
+		{
+			name: "deleted by us and changed by them",
+			ours: `
+- {id: "2", tags: [git], command: git status}
+`,
+			theirs: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: Show the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+`,
+			want: `
+- {id: "2", tags: [git], command: git status}
+- {id: "1", tags: [k8s], command: kubectl get pods, description: Show the pods, uses: 2}
+`,
+		},
+		{
+			name: "changed by us and deleted by them",
+			ours: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: Show the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+`,
+			theirs: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List the pods, uses: 2}
+`,
+			want: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: Show the pods, uses: 2}
+`,
+		},
+		{
+			name: "tags added and removed on both sides",
+			ours: `
+- {id: "1", tags: [pods], command: kubectl get pods, description: List the pods, uses: 2}
+- {id: "2", tags: [git, vcs], command: git status}
+`,
+			theirs: `
+- {id: "1", tags: [k8s, kubectl], command: kubectl get pods, description: List the pods, uses: 2}
+- {id: "2", tags: [vcs, status], command: git status}
+`,
+			want: `
+- {id: "1", tags: [pods, kubectl], command: kubectl get pods, description: List the pods, uses: 2}
+- {id: "2", tags: [vcs, status], command: git status}
+`,
+		},
+		{
+			name: "uses counted on both sides",
+			ours: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List the pods, uses: 5}
+- {id: "2", tags: [git], command: git status, uses: 1}
+`,
+			theirs: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List the pods, uses: 3}
+- {id: "2", tags: [git], command: git status}
+`,
+			want: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List the pods, uses: 6}
+- {id: "2", tags: [git], command: git status, uses: 1}
+`,
+		},
+		{
+			name: "different notes added on both sides",
+			ours: base + `
+- {id: "3", command: ls}
+`,
+			theirs: base + `
+- {id: "4", command: pwd}
+`,
+			want: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+- {id: "3", command: ls}
+- {id: "4", command: pwd}
+`,
+		},
+		{
+			name: "same command added on both sides",
+			ours: base + `
+- {id: "3", tags: [fs], command: ls -la, uses: 1}
+- {id: "5", command: df -h, description: Disk space}
+`,
+			theirs: base + `
+- {id: "4", tags: [files], command: ls -la, description: List the files, uses: 2}
+- {id: "6", command: df -h, description: Free space}
+`,
+			want: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+- {id: "3", tags: [fs, files], command: ls -la, description: List the files, uses: 3}
+- {id: "5", command: df -h, description: Disk space}
+`,
+			wantConflicts: "5: [description]",
+		},
+		{
+			name: "same ID added on both sides",
+			ours: base + `
+- {id: "3", command: ls, description: List}
+`,
+			theirs: base + `
+- {id: "3", command: ls, description: Show}
+`,
+			want: `
+- {id: "1", tags: [k8s], command: kubectl get pods, description: List the pods, uses: 2}
+- {id: "2", tags: [git], command: git status}
+- {id: "3", command: ls, description: List}
+`,
+			wantConflicts: "3: [description]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Notes(parse(t, base), parse(t, tt.ours), parse(t, tt.theirs))
+
+			got, err := Marshal(result.Notes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := Marshal(parse(t, tt.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("Notes() =\n%s\nwant\n%s", got, want)
+			}
+
+			conflicts := []string{}
+			for _, c := range result.Conflicts {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %v", c.ID, c.Fields))
+			}
+			if got := strings.Join(conflicts, ", "); got != tt.wantConflicts {
+				t.Errorf("conflicts = %q, want %q", got, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestResolveConflictOfSameCommand(t *testing.T) {
+	result := Notes(nil, parse(t, `[{id: "1", command: ls, description: List}]`), parse(t, `[{id: "2", command: ls, description: Show}]`))
+	if len(result.Conflicts) != 1 {
+		t.Fatalf("got %d conflicts, want 1", len(result.Conflicts))
+	}
+	result.Conflicts[0].Resolve("description", result.Conflicts[0].Theirs["description"])
+	if len(result.Notes) != 1 || result.Notes[0].ID() != "1" || result.Notes[0]["description"] != "Show" {
+		t.Errorf("notes = %v, want our note with their description", result.Notes)
+	}
+}
+
+func TestFilesKeepsUnknownFields(t *testing.T) {
+	baseFile := `- id: "1"
+  command: kubectl get pods
+  color: blue
+`
+	ours := `- id: "1"
+  command: kubectl get pods
+  description: List the pods
+  color: blue
+  x-owner: ops
+`
+	theirs := `- id: "1"
+  command: kubectl get pods
+  color: red
+- id: "2"
+  command: git status
+  future:
+    nested: [1, 2]
+`
+	want := `- id: "1"
+  command: kubectl get pods
+  description: List the pods
+  color: red
+  x-owner: ops
+- id: "2"
+  command: git status
+  future:
+    nested:
+        - 1
+        - 2
+`
+	data, result, err := Files([]byte(baseFile), []byte(ours), []byte(theirs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("Files() =\n%s\nwant\n%s", data, want)
+	}
+	if len(result.Conflicts) != 0 {
+		t.Errorf("conflicts = %v, want none", result.Conflicts)
+	}
+
+	// the merged file reads back as it is
+	notes, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Marshal(notes)
+	if err != nil || string(again) != want {
+		t.Errorf("Marshal() of the merge = %s, %v", again, err)
+	}
+
+	if _, _, err := Files([]byte(baseFile), []byte("- {"), []byte(theirs)); err == nil || !strings.HasPrefix(err.Error(), "our version:") {
+		t.Errorf("Files() of an invalid file = %v", err)
+	}
+}
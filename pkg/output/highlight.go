@@ -0,0 +1,139 @@
+package output
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// scriptIndent indents the lines of multi-line commands under the Command label
+const scriptIndent = "    "
+
+var (
+	plainColor    = color.New(color.FgRed)
+	keywordColor  = color.New(color.FgCyan, color.Bold)
+	stringColor   = color.New(color.FgGreen)
+	variableColor = color.New(color.FgMagenta)
+	commentColor  = color.New(color.FgHiBlack)
+)
+
+var shellKeywords = words("if then else elif fi for while until do done case esac in function return local export select break continue")
+
+var pythonKeywords = words("def class if elif else for while in return import from as with try except finally raise pass break continue lambda yield not and or is None True False")
+
+var jsKeywords = words("function const let var if else for while return import from export class new try catch finally throw async await of in")
+
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(list) {
+		set[word] = true
+	}
+	return set
+}
+
+// printCommand prints the command of a note, multi-line commands are indented and highlighted
+func printCommand(note Note, reveal bool) {
+	label := "Command:"
+	if note.Shell != "" {
+		label = "Command (" + note.Shell + "):"
+	}
+
+	switch {
+	case note.Secret && !reveal:
+		color.HiBlue("%s %s %s \n", label, color.RedString(SecretMask), color.WhiteString("(secret, use --reveal to show it)"))
+	case !strings.Contains(note.Command, "\n"):
+		color.HiBlue("%s %s \n", label, color.RedString(note.Command))
+	default:
+		color.HiBlue("%s \n", label)
+		for _, line := range strings.Split(note.Command, "\n") {
+			color.Output.Write([]byte(scriptIndent + highlight(line, note.Shell) + "\n"))
+		}
+	}
+}
+
+// highlight colors the comments, strings, variables and keywords of a line of a script
+func highlight(line string, shell string) string {
+	keywords, comment := shellKeywords, "#"
+	switch strings.ToLower(shell) {
+	case "python", "python3":
+		keywords = pythonKeywords
+	case "node", "javascript":
+		keywords, comment = jsKeywords, "//"
+	case "ruby", "perl", "pwsh", "powershell", "cmd":
+		keywords = nil
+	}
+
+	var b strings.Builder
+	// plain text is written in runs, not rune by rune
+	plain := []rune{}
+	write := func(c *color.Color, text string) {
+		if len(plain) > 0 {
+			b.WriteString(plainColor.Sprint(string(plain)))
+			plain = plain[:0]
+		}
+		if text != "" {
+			b.WriteString(c.Sprint(text))
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case strings.HasPrefix(string(runes[i:]), comment) && (i == 0 || unicode.IsSpace(runes[i-1])):
+			write(commentColor, string(runes[i:]))
+			return b.String()
+
+		case r == '"' || r == '\'' || r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' && r != '\'' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(runes))
+			write(stringColor, string(runes[i:end]))
+			i = end
+
+		case r == '$' && i+1 < len(runes):
+			end := i + 1
+			if runes[end] == '{' {
+				for end < len(runes) && runes[end] != '}' {
+					end++
+				}
+				end = min(end+1, len(runes))
+			} else {
+				for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+					end++
+				}
+			}
+			if end == i+1 {
+				// e.g. $( or a lone $
+				end++
+			}
+			write(variableColor, string(runes[i:end]))
+			i = end
+
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '-') {
+				end++
+			}
+			word := string(runes[i:end])
+			if keywords[word] {
+				write(keywordColor, word)
+			} else {
+				plain = append(plain, runes[i:end]...)
+			}
+			i = end
+
+		default:
+			plain = append(plain, r)
+			i++
+		}
+	}
+	write(plainColor, "")
+	return b.String()
+}
@@ -3,9 +3,10 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 
-	"github.com/atotto/clipboard"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
 	"github.com/fatih/color"
 )
 
@@ -15,6 +16,29 @@ type Note struct {
 	Tags        []string `json:"tags"`
 	Command     string   `json:"command"`
 	Description string   `json:"description"`
+	Uses        int      `json:"uses,omitempty"`
+	Secret      bool     `json:"secret,omitempty"`
+	Shell       string   `json:"shell,omitempty"`
+	Score       float64  `json:"score,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	// Sources lists the ID and description of the note in each profile it was found in
+	Sources []Source `json:"sources,omitempty"`
+}
+
+// Source is a note of a profile, when notes of several profiles are merged
+type Source struct {
+	Source      string `json:"source"`
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// SecretMask replaces the command of secret notes unless they're revealed
+const SecretMask = "********"
+
+// Group is notes grouped by their tags, as Print lists them
+type Group struct {
+	Tags  []string `json:"tags"`
+	Notes []Note   `json:"notes"`
 }
 
 // orderedNote struct
@@ -23,26 +47,22 @@ type orderedNote struct {
 	Notes []Note
 }
 
-// Print print the notes
-func Print(note interface{}) {
-	notes := []Note{}
-	s, _ := json.MarshalIndent(note, "", "\t")
-	if err := json.Unmarshal(s, &notes); err != nil {
-		color.Red("Error while unmarshalling notes: %s", err)
-	}
+// Print print the notes grouped by tags, it returns them in the order shown.
+// When several notes are shown they're numbered, e.g. to copy one with --copy.
+// The commands of secret notes are masked unless reveal is set.
+func Print(note interface{}, reveal bool) []Note {
+	notes := toNotes(note)
+	shown := []Note{}
 
 	if len(notes) == 0 {
-		color.Yellow("No notes found")
-		return
+		logger.Info("No notes found")
+		return shown
 	}
 
 	orderedNotes := processNotes(notes)
 	maxLength := getMaxLength(orderedNotes)
 	numberOfNotes := len(orderedNotes)
-
-	if numberOfNotes == 1 {
-		clipboard.WriteAll(orderedNotes[0].Notes[0].Command)
-	}
+	numbered := numberOfNotes > 1 || len(orderedNotes[0].Notes) > 1
 
 	for _, orderedNote := range orderedNotes {
 		numberOfNotes--
@@ -65,10 +85,21 @@ func Print(note interface{}) {
 		color.Yellow("%s %s %s", leftPad, tag, rightPad)
 
 		for _, note := range orderedNote.Notes {
-			color.HiBlue("ID: %s \n", color.WhiteString(note.ID))
+			shown = append(shown, note)
+			if numbered {
+				color.HiBlue("ID: %s %s\n", color.WhiteString(note.ID), color.HiBlackString("[%d]", len(shown)))
+			} else {
+				color.HiBlue("ID: %s \n", color.WhiteString(note.ID))
+			}
 			color.HiBlue("Tags: %s \n", color.GreenString(strings.Join(note.Tags, ", ")))
-			color.HiBlue("Command: %s \n", color.RedString(note.Command))
+			printCommand(note, reveal)
 			color.HiBlue("Description: %s \n", color.WhiteString(note.Description))
+			if note.Source != "" {
+				color.HiBlue("Source: %s \n", color.MagentaString(note.Source))
+			}
+			for _, source := range note.Sources[min(1, len(note.Sources)):] {
+				printSource(source, note.Description)
+			}
 			// add full line only if there are more tags
 			if numberOfNotes == 0 {
 				color.Yellow("%s", strings.Repeat("-", 42+maxLength))
@@ -77,6 +108,83 @@ func Print(note interface{}) {
 			}
 		}
 	}
+
+	return shown
+}
+
+// Groups groups the notes by their tags as Print does, e.g. for the web UI.
+// The commands of secret notes are masked unless reveal is set.
+func Groups(note interface{}, reveal bool) []Group {
+	groups := []Group{}
+	for _, orderedNote := range processNotes(Notes(note, reveal)) {
+		groups = append(groups, Group{Tags: orderedNote.Tags, Notes: orderedNote.Notes})
+	}
+	return groups
+}
+
+// PrintJSON print the notes as a JSON array, in the given order, and returns them.
+// The commands of secret notes are masked unless reveal is set.
+func PrintJSON(note interface{}, reveal bool) []Note {
+	notes := toNotes(note)
+	s, err := json.MarshalIndent(Mask(notes, reveal), "", "  ")
+	if err != nil {
+		logger.Error("Error while marshalling notes: %s", err)
+		return notes
+	}
+	fmt.Println(string(s))
+	return notes
+}
+
+// Notes converts the notes of any storage to output notes, e.g. to encode them as JSON.
+// The commands of secret notes are masked unless reveal is set.
+func Notes(note interface{}, reveal bool) []Note {
+	return Mask(toNotes(note), reveal)
+}
+
+// Mask returns a copy of the notes with the commands of secret notes masked, unless reveal is set
+func Mask(notes []Note, reveal bool) []Note {
+	masked := make([]Note, len(notes))
+	for i, n := range notes {
+		if n.Secret && !reveal {
+			n.Command = SecretMask
+		}
+		masked[i] = n
+	}
+	return masked
+}
+
+// toNotes converts the notes of any storage to output notes
+func toNotes(note interface{}) []Note {
+	notes := []Note{}
+	if note == nil {
+		return notes
+	}
+
+	s, _ := json.Marshal(note)
+	if err := json.Unmarshal(s, &notes); err != nil {
+		// a single note
+		single := Note{}
+		if err := json.Unmarshal(s, &single); err != nil {
+			logger.Error("Error while unmarshalling notes: %s", err)
+			return notes
+		}
+		notes = append(notes, single)
+	}
+
+	// scores are sums of weights, e.g. 3.5999999999999996
+	for i := range notes {
+		notes[i].Score = math.Round(notes[i].Score*100) / 100
+	}
+	return notes
+}
+
+// printSource prints the ID of the note in another profile, and its description when it's different
+func printSource(source Source, description string) {
+	line := fmt.Sprintf("  %s: %s", color.MagentaString(source.Source), color.WhiteString(source.ID))
+	if source.Description != description {
+		line += " " + color.WhiteString("(%s)", source.Description)
+	}
+	color.HiBlue("%s \n", line)
 }
 
 // PrintTags print the tags
@@ -1,12 +1,19 @@
 package prompt
 
 import (
-	"fmt"
+	"os"
 	"strings"
 
+	"github.com/carloscastrojumo/remindme/pkg/logger"
 	"github.com/manifoldco/promptui"
+	"github.com/mattn/go-isatty"
 )
 
+// IsInteractive reports whether the user can be prompted, i.e. stdin is a terminal
+func IsInteractive() bool {
+	return isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())
+}
+
 // ForString prompts the user for a string
 func ForString(label string) string {
 	prompt := promptui.Prompt{
@@ -16,7 +23,7 @@ func ForString(label string) string {
 	result, err := prompt.Run()
 
 	if err != nil {
-		fmt.Printf("Prompt failed %v\n", err)
+		logger.Warn("Prompt failed %v", err)
 		return ""
 	}
 
@@ -32,9 +39,31 @@ func ForStringArray(label string) []string {
 	result, err := prompt.Run()
 
 	if err != nil {
-		fmt.Printf("Prompt failed %v\n", err)
+		logger.Warn("Prompt failed %v", err)
 		return []string{}
 	}
 
 	return strings.Split(result, ",")
 }
+
+// Confirm asks the user a yes or no question, anything but yes is a no
+func Confirm(label string) bool {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// ForChoice prompts the user to choose one of the items and returns its position
+func ForChoice(label string, items []string) (int, error) {
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+	}
+
+	index, _, err := prompt.Run()
+	return index, err
+}
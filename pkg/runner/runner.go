@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// how the arguments given to a note are passed to its interpreter
+const (
+	// appended to the script, quoted by quote, e.g. cmd and PowerShell
+	argsInScript = iota
+	// after the script, e.g. "python3 -c script args"
+	argsAfter
+	// after the script and its name, which is $0, e.g. "sh -c script rmm args"
+	argsAfterName
+)
+
+type interpreter struct {
+	argv []string
+	args int
+	// quote quotes an argument appended to the script
+	quote func(arg string) string
+}
+
+// interpreters run a script given as an argument, by shell or language
+var interpreters = map[string]interpreter{
+	"sh":         {[]string{"sh", "-c"}, argsAfterName, nil},
+	"bash":       {[]string{"bash", "-c"}, argsAfterName, nil},
+	"zsh":        {[]string{"zsh", "-c"}, argsAfterName, nil},
+	"ksh":        {[]string{"ksh", "-c"}, argsAfterName, nil},
+	"dash":       {[]string{"dash", "-c"}, argsAfterName, nil},
+	"fish":       {[]string{"fish", "-c"}, argsAfter, nil},
+	"python":     {[]string{"python3", "-c"}, argsAfter, nil},
+	"python3":    {[]string{"python3", "-c"}, argsAfter, nil},
+	"ruby":       {[]string{"ruby", "-e"}, argsAfter, nil},
+	"perl":       {[]string{"perl", "-e"}, argsAfter, nil},
+	"node":       {[]string{"node", "-e"}, argsAfter, nil},
+	"javascript": {[]string{"node", "-e"}, argsAfter, nil},
+	"pwsh":       {[]string{"pwsh", "-NoProfile", "-Command"}, argsInScript, quotePowerShell},
+	"powershell": {[]string{"powershell", "-NoProfile", "-Command"}, argsInScript, quotePowerShell},
+	"cmd":        {[]string{"cmd", "/C"}, argsInScript, quoteCmd},
+}
+
+// extensions are the file extensions of the scripts of each shell or language, for editors
+var extensions = map[string]string{
+	"fish":       ".fish",
+	"python":     ".py",
+	"python3":    ".py",
+	"ruby":       ".rb",
+	"perl":       ".pl",
+	"node":       ".js",
+	"javascript": ".js",
+	"pwsh":       ".ps1",
+	"powershell": ".ps1",
+	"cmd":        ".cmd",
+}
+
+// Extension returns the file extension of a script run by the shell, .sh for the shells not known
+func Extension(shell string) string {
+	if extension, ok := extensions[strings.ToLower(shell)]; ok {
+		return extension
+	}
+	return ".sh"
+}
+
+// DefaultShell returns the shell running notes without one, the user's shell or sh
+func DefaultShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return filepath.Base(shell)
+	}
+	if runtime.GOOS == "windows" {
+		return "cmd"
+	}
+	return "sh"
+}
+
+// Command returns the command running a script with a shell or language interpreter, the default
+// shell when none is given. Unknown shells are run as "shell -c script rmm args", as POSIX shells. The command uses the
+// standard input and outputs of rmm.
+func Command(ctx context.Context, script string, shell string, args []string) *exec.Cmd {
+	if shell == "" {
+		shell = DefaultShell()
+	}
+
+	in, ok := interpreters[strings.ToLower(shell)]
+	if !ok {
+		in = interpreter{argv: []string{shell, "-c"}, args: argsAfterName}
+	}
+
+	argv := append([]string{}, in.argv...)
+	switch in.args {
+	case argsAfterName:
+		argv = append(append(argv, script, "rmm"), args...)
+	case argsAfter:
+		argv = append(append(argv, script), args...)
+	default:
+		for _, arg := range args {
+			script += " " + in.quote(arg)
+		}
+		argv = append(argv, script)
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// quotePowerShell quotes an argument as a PowerShell string, nothing is expanded in single quotes
+func quotePowerShell(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", "''") + "'"
+}
+
+// quoteCmd quotes an argument for cmd, metacharacters such as & and | are literal between double quotes.
+// Percent signs are escaped outside of the quotes, variables would be expanded between them.
+func quoteCmd(arg string) string {
+	parts := strings.Split(arg, "%")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, "^%")
+}
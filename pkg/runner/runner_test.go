@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestCommandArguments(t *testing.T) {
+	tests := []struct {
+		shell string
+		args  []string
+		want  []string
+	}{
+		{"bash", []string{"a b", "$HOME"}, []string{"bash", "-c", "script", "rmm", "a b", "$HOME"}},
+		{"python", []string{"a b"}, []string{"python3", "-c", "script", "a b"}},
+		// unknown shells get the arguments as POSIX shells
+		{"/opt/bin/mysh", []string{"a b", "c;d"}, []string{"/opt/bin/mysh", "-c", "script", "rmm", "a b", "c;d"}},
+		{"pwsh", []string{"a b", "it's", "$env:HOME"}, []string{"pwsh", "-NoProfile", "-Command", `script 'a b' 'it''s' '$env:HOME'`}},
+		{"cmd", []string{"a b", "x&y", `say "hi"`, "100%PATH%"}, []string{"cmd", "/C", `script "a b" "x&y" "say ""hi""" "100"^%"PATH"^%""`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			cmd := Command(context.Background(), "script", tt.shell, tt.args)
+			if !slices.Equal(cmd.Args, tt.want) {
+				t.Errorf("got %q, want %q", cmd.Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandRunsUnknownShellWithArguments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX shell")
+	}
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	// the shell is given by path, which isn't a known interpreter
+	cmd := Command(context.Background(), `printf '%s|' "$@"`, sh, []string{"a b", "c;echo injected"})
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a b|c;echo injected|"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,276 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// qualifiers maps the field qualifiers of the query language to note fields
+var qualifiers = map[string]string{
+	"tag":         "tags",
+	"tags":        "tags",
+	"cmd":         "command",
+	"command":     "command",
+	"desc":        "description",
+	"description": "description",
+}
+
+// Term is a single condition of a query, e.g. tag:k8s, -deprecated or /get.*pods/i
+type Term struct {
+	// Fields searched by the term, the query default fields when not qualified
+	Fields []string
+	Value  string
+	// Regex is set when the term is a regular expression, Value holds its source
+	Regex  *regexp.Regexp
+	Negate bool
+}
+
+// Clause is a group of terms that must all match
+type Clause struct {
+	Terms []Term
+}
+
+// Query is a parsed search, it matches when any of its clauses matches.
+// Clauses are separated by OR in the query, terms are ANDed by default.
+type Query struct {
+	Input         string
+	Clauses       []Clause
+	CaseSensitive bool
+	// Fields searched by unqualified terms, e.g. to parse the input again on a server
+	Fields []string
+	// Fuzzy makes plain words also match with typos and abbreviations when ranking
+	Fuzzy bool
+}
+
+// Document is the searchable content of a note
+type Document struct {
+	Command     string
+	Description string
+	Tags        []string
+	Uses        int
+}
+
+// Parse parses a query, unqualified terms search the default fields
+func Parse(input string, defaultFields []string, caseSensitive bool) (*Query, error) {
+	if len(defaultFields) == 0 {
+		defaultFields = Locations
+	}
+
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &Query{Input: input, Fields: defaultFields, CaseSensitive: caseSensitive}
+	clause := Clause{}
+	for _, token := range tokens {
+		if token.text == "OR" && !token.quoted {
+			if len(clause.Terms) == 0 {
+				return nil, errors.New("OR must be between terms")
+			}
+			query.Clauses = append(query.Clauses, clause)
+			clause = Clause{}
+			continue
+		}
+
+		term, err := parseTerm(token, defaultFields, caseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		clause.Terms = append(clause.Terms, term)
+	}
+
+	if len(clause.Terms) == 0 {
+		if len(query.Clauses) > 0 {
+			return nil, errors.New("OR must be between terms")
+		}
+		return nil, errors.New("the query is empty")
+	}
+	query.Clauses = append(query.Clauses, clause)
+
+	return query, nil
+}
+
+// Match reports whether the document matches the query exactly, see Score for fuzzy matching
+func (q *Query) Match(doc Document) bool {
+	for _, clause := range q.Clauses {
+		if q.matchClause(clause, doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Query) matchClause(clause Clause, doc Document) bool {
+	for _, term := range clause.Terms {
+		if q.matchTerm(term, doc) == term.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Query) matchTerm(term Term, doc Document) bool {
+	for _, field := range term.Fields {
+		for _, value := range doc.values(field) {
+			if q.matchValue(term, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q *Query) matchValue(term Term, value string) bool {
+	if term.Regex != nil {
+		return term.Regex.MatchString(value)
+	}
+	if q.CaseSensitive {
+		return strings.Contains(value, term.Value)
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(term.Value))
+}
+
+func (d Document) values(field string) []string {
+	switch field {
+	case "command":
+		return []string{d.Command}
+	case "description":
+		return []string{d.Description}
+	case "tags":
+		return d.Tags
+	}
+	return nil
+}
+
+// token is a part of the query separated by spaces, quoted parts are kept together.
+// For quoted tokens prefix holds the negation and qualifier before the quotes.
+type token struct {
+	prefix string
+	text   string
+	quoted bool
+}
+
+func tokenize(input string) ([]token, error) {
+	tokens := []token{}
+	var current strings.Builder
+	quoted := false
+	inQuotes := false
+	inRegex := false
+
+	prefix := ""
+	flush := func() {
+		if current.Len() > 0 || quoted {
+			tokens = append(tokens, token{prefix: prefix, text: current.String(), quoted: quoted})
+		}
+		current.Reset()
+		prefix = ""
+		quoted = false
+	}
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inRegex:
+			current.WriteRune(r)
+			if r == '\\' && i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			} else if r == '/' {
+				inRegex = false
+			}
+		case inQuotes:
+			if r == '"' {
+				inQuotes = false
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' && !quoted && startsValue(current.String()):
+			inQuotes = true
+			quoted = true
+			prefix = current.String()
+			current.Reset()
+		case r == '/' && startsValue(current.String()):
+			inRegex = true
+			current.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuotes {
+		return nil, errors.New("unterminated quote")
+	}
+	flush()
+	return tokens, nil
+}
+
+// startsValue reports whether the next character starts the value of a term,
+// i.e. the term so far is empty, a negation or a qualifier
+func startsValue(prefix string) bool {
+	prefix = strings.TrimPrefix(prefix, "-")
+	if prefix == "" {
+		return true
+	}
+	if !strings.HasSuffix(prefix, ":") {
+		return false
+	}
+	_, ok := qualifiers[strings.ToLower(strings.TrimSuffix(prefix, ":"))]
+	return ok
+}
+
+func parseTerm(tok token, defaultFields []string, caseSensitive bool) (Term, error) {
+	term := Term{Fields: defaultFields}
+	text := tok.text
+	if tok.quoted {
+		// the negation and qualifier are before the quotes, the value is literal
+		text = tok.prefix
+	}
+
+	if strings.HasPrefix(text, "-") && (len(text) > 1 || tok.quoted) {
+		term.Negate = true
+		text = text[1:]
+	}
+
+	if i := strings.Index(text, ":"); i > 0 {
+		if field, ok := qualifiers[strings.ToLower(text[:i])]; ok {
+			term.Fields = []string{field}
+			text = text[i+1:]
+		}
+	}
+
+	if tok.quoted {
+		if tok.text == "" {
+			return term, errors.New("empty phrase")
+		}
+		term.Value = tok.text
+		return term, nil
+	}
+
+	// /pattern/ or /pattern/i, anything else starting with a slash (e.g. a path) is literal
+	if end := strings.LastIndex(text, "/"); strings.HasPrefix(text, "/") && end > 0 && strings.Trim(text[end+1:], "i") == "" {
+		pattern, flags := text[1:end], text[end+1:]
+		if flags != "" || !caseSensitive {
+			pattern = "(?i)" + pattern
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return term, fmt.Errorf("invalid regular expression %q: %w", text, err)
+		}
+		term.Value = text[1:end]
+		term.Regex = re
+		return term, nil
+	}
+
+	if text == "" {
+		return term, fmt.Errorf("empty term %q", tok.text)
+	}
+	term.Value = text
+	return term, nil
+}
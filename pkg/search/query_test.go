@@ -0,0 +1,158 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+// describe writes the parsed query back, e.g. "command:kubectl -tags:old OR command:get.*pods=~(?i)get.*pods"
+func describe(q *Query) string {
+	clauses := []string{}
+	for _, clause := range q.Clauses {
+		terms := []string{}
+		for _, term := range clause.Terms {
+			text := strings.Join(term.Fields, ",") + ":" + term.Value
+			if term.Negate {
+				text = "-" + text
+			}
+			if term.Regex != nil {
+				text += "=~" + term.Regex.String()
+			}
+			terms = append(terms, text)
+		}
+		clauses = append(clauses, strings.Join(terms, " "))
+	}
+	return strings.Join(clauses, " OR ")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input         string
+		caseSensitive bool
+		want          string
+	}{
+		// words and qualifiers
+		{input: "kubectl pods", want: "command:kubectl command:pods"},
+		{input: "tag:k8s cmd:get desc:list", want: "tags:k8s command:get description:list"},
+		{input: "TAGS:k8s Command:get description:list", want: "tags:k8s command:get description:list"},
+		{input: "http://localhost:8080", want: "command:http://localhost:8080"},
+		{input: "tag:a:b", want: "tags:a:b"},
+
+		// phrases
+		{input: `"get pods" all`, want: "command:get pods command:all"},
+		{input: `"get pods" -n`, want: "command:get pods -command:n"},
+		{input: `desc:"list the pods"`, want: "description:list the pods"},
+		{input: `-tag:"old stuff" kubectl`, want: "-tags:old stuff command:kubectl"},
+		{input: `"OR"`, want: "command:OR"},
+		{input: `say"hello world"`, want: `command:say"hello command:world"`},
+
+		// negation
+		{input: "kubectl -deprecated", want: "command:kubectl -command:deprecated"},
+		{input: "-tag:old", want: "-tags:old"},
+		{input: "ls -", want: "command:ls command:-"},
+
+		// regular expressions
+		{input: "/get.*pods/", want: "command:get.*pods=~(?i)get.*pods"},
+		{input: "/Get.*Pods/", caseSensitive: true, want: "command:Get.*Pods=~Get.*Pods"},
+		{input: "/Get.*Pods/i", caseSensitive: true, want: "command:Get.*Pods=~(?i)Get.*Pods"},
+		{input: "cmd:/^git (push|pull)/", want: "command:^git (push|pull)=~(?i)^git (push|pull)"},
+		{input: `-/a\/b c/`, want: `-command:a\/b c=~(?i)a\/b c`},
+		{input: "/usr/bin", want: "command:/usr/bin"},
+		{input: "/tmp", want: "command:/tmp"},
+
+		// OR
+		{input: "kubectl OR helm", want: "command:kubectl OR command:helm"},
+		{input: "tag:k8s pods OR tag:docker -ps", want: "tags:k8s command:pods OR tags:docker -command:ps"},
+		{input: "kubectl or helm", want: "command:kubectl command:or command:helm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input, []string{"command"}, tt.caseSensitive)
+			if err != nil {
+				t.Fatalf("Parse() = %v", err)
+			}
+			if got := describe(q); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDefaultFields(t *testing.T) {
+	q, err := Parse("pods", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(q); got != "command,description,tags:pods" {
+		t.Errorf("Parse() = %s", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "the query is empty"},
+		{"   ", "the query is empty"},
+		{`"get pods`, "unterminated quote"},
+		{`tag:"k8s`, "unterminated quote"},
+		{`""`, "empty phrase"},
+		{"tag:", `empty term "tag:"`},
+		{"-tag:", `empty term "-tag:"`},
+		{"/get(pods/", "invalid regular expression"},
+		{"/[a-/i", "invalid regular expression"},
+		{"OR kubectl", "OR must be between terms"},
+		{"kubectl OR", "OR must be between terms"},
+		{"kubectl OR OR helm", "OR must be between terms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input, nil, false)
+			if err == nil {
+				t.Fatalf("Parse() = %s, want an error", describe(q))
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Parse() = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	doc := Document{Command: "kubectl get pods -A", Description: "List all the pods", Tags: []string{"k8s", "Pods"}}
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"kubectl pods", true},
+		{"KUBECTL", true},
+		{"tag:pods", true},
+		{"tag:docker", false},
+		{"cmd:list", false},
+		{`desc:"all the pods"`, true},
+		{`"pods all"`, false},
+		{"kubectl -tag:k8s", false},
+		{"-helm", true},
+		{"helm OR tag:k8s", true},
+		{"helm OR docker", false},
+		{"/get\\s+pods/", true},
+		{"cmd:/^get/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input, nil, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := q.Match(doc); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	q, _ := Parse("kubectl", nil, true)
+	if q.Match(Document{Command: "KUBECTL get pods"}) {
+		t.Error("case sensitive Match() ignored the case")
+	}
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fieldWeights ranks matches in commands above tags and tags above descriptions
+var fieldWeights = map[string]float64{
+	"command":     3,
+	"tags":        2,
+	"description": 1,
+}
+
+const (
+	exactScore       = 1.0
+	wordPrefixBonus  = 0.2
+	typoScore        = 0.6
+	subsequenceScore = 0.3
+	// maxSubsequenceSpread limits how far apart the characters of a subsequence can be
+	maxSubsequenceSpread = 6
+	// usesWeight is how much each order of magnitude of uses adds to the score
+	usesWeight = 0.1
+)
+
+// Ranked is a position in a list of documents and its score
+type Ranked struct {
+	Index int
+	Score float64
+}
+
+// Rank scores the documents against the query and returns the matching ones, best first
+func Rank(q *Query, docs []Document) []Ranked {
+	ranked := []Ranked{}
+	for i, doc := range docs {
+		if score := q.Score(doc); score > 0 {
+			ranked = append(ranked, Ranked{Index: i, Score: score})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// Score returns how well the document matches the query, 0 when it doesn't match.
+// Fuzzy queries also match words with typos and abbreviations, at a lower score.
+func (q *Query) Score(doc Document) float64 {
+	best := 0.0
+	for _, clause := range q.Clauses {
+		if score := q.scoreClause(clause, doc); score > best {
+			best = score
+		}
+	}
+	if best == 0 {
+		return 0
+	}
+	return best * (1 + usesWeight*math.Log1p(float64(doc.Uses)))
+}
+
+func (q *Query) scoreClause(clause Clause, doc Document) float64 {
+	total := 0.0
+	for _, term := range clause.Terms {
+		if term.Negate {
+			// negated terms are never fuzzy, a typo shouldn't exclude a note
+			if q.matchTerm(term, doc) {
+				return 0
+			}
+			continue
+		}
+
+		score := q.scoreTerm(term, doc)
+		if score == 0 {
+			return 0
+		}
+		total += score
+	}
+
+	// a clause made only of negations matches everything that isn't excluded
+	if total == 0 {
+		total = exactScore
+	}
+	return total
+}
+
+func (q *Query) scoreTerm(term Term, doc Document) float64 {
+	best := 0.0
+	for _, field := range term.Fields {
+		for _, value := range doc.values(field) {
+			if score := q.scoreValue(term, value) * fieldWeights[field]; score > best {
+				best = score
+			}
+		}
+	}
+	return best
+}
+
+func (q *Query) scoreValue(term Term, value string) float64 {
+	if term.Regex != nil {
+		if term.Regex.MatchString(value) {
+			return exactScore
+		}
+		return 0
+	}
+
+	needle, haystack := term.Value, value
+	if !q.CaseSensitive {
+		needle, haystack = strings.ToLower(needle), strings.ToLower(haystack)
+	}
+
+	if i := strings.Index(haystack, needle); i >= 0 {
+		if previous, _ := utf8.DecodeLastRuneInString(haystack[:i]); i == 0 || !isWordRune(previous) {
+			return exactScore + wordPrefixBonus
+		}
+		return exactScore
+	}
+
+	// phrases are matched exactly
+	if !q.Fuzzy || strings.ContainsRune(needle, ' ') {
+		return 0
+	}
+
+	if score := typoMatch(needle, haystack); score > 0 {
+		return score
+	}
+	return subsequenceMatch(needle, haystack)
+}
+
+// typoMatch compares the needle to every word of the haystack allowing an edit distance
+// of one for every four characters
+func typoMatch(needle string, haystack string) float64 {
+	maxDistance := len([]rune(needle)) / 4
+	if maxDistance == 0 {
+		return 0
+	}
+
+	best := 0.0
+	for _, word := range strings.FieldsFunc(haystack, func(r rune) bool { return !isWordRune(r) }) {
+		distance := levenshtein(needle, word)
+		if distance > maxDistance {
+			continue
+		}
+		score := typoScore * (1 - float64(distance)/float64(len([]rune(needle))+1))
+		if score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// subsequenceMatch matches the characters of the needle in order, e.g. "kgp" in "kubectl get pods",
+// the closer the characters the higher the score
+func subsequenceMatch(needle string, haystack string) float64 {
+	n := []rune(needle)
+	if len(n) < 3 {
+		return 0
+	}
+
+	start, i := -1, 0
+	for j, r := range []rune(haystack) {
+		if r == n[i] {
+			if start < 0 {
+				start = j
+			}
+			i++
+			if i == len(n) {
+				span := j - start + 1
+				if span > maxSubsequenceSpread*len(n) {
+					return 0
+				}
+				return subsequenceScore * float64(len(n)) / float64(span)
+			}
+		}
+	}
+	return 0
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(t)]
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+)
+
+var rankDocs = []Document{
+	{Command: "docker ps -a", Description: "List the containers", Tags: []string{"docker"}},
+	{Command: "kubectl get pods", Description: "List the pods", Tags: []string{"k8s"}},
+	{Command: "helm list", Description: "List the releases, kubectl isn't needed", Tags: []string{"helm"}},
+	{Command: "kubectl logs -f web", Description: "Follow the logs", Tags: []string{"kubectl"}},
+	{Command: "mykubectl version", Description: "Print the version"},
+	{Command: "kubectl get pods", Description: "List the pods, often", Tags: []string{"k8s"}, Uses: 50},
+	{Command: "git status", Description: "Show the working tree"},
+}
+
+// order lists the positions of the ranked documents
+func order(ranked []Ranked) string {
+	positions := []int{}
+	for _, r := range ranked {
+		positions = append(positions, r.Index)
+	}
+	return fmt.Sprint(positions)
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		input string
+		fuzzy bool
+		want  string
+	}{
+		// commands first, then tags, then descriptions, words before parts of words
+		{input: "kubectl", want: "[5 1 3 4 2]"},
+		// more uses rank the same match higher
+		{input: "pods", want: "[5 1]"},
+		{input: "list", want: "[2 5 0 1]"},
+		{input: "tag:k8s OR docker", want: "[0 5 1]"},
+		{input: "kubectl -tag:k8s", want: "[3 4 2]"},
+		{input: "-kubectl", want: "[0 6]"},
+		{input: "/^kubectl/", want: "[5 1 3]"},
+		// exact matches before typos and abbreviations
+		{input: "kubctl", want: "[]"},
+		{input: "kubctl", fuzzy: true, want: "[5 1 3 4 2]"},
+		{input: "kgp", fuzzy: true, want: "[5 1]"},
+		{input: "git", fuzzy: true, want: "[6]"},
+		{input: `"get pod"`, fuzzy: true, want: "[5 1]"},
+		{input: `"gte pods"`, fuzzy: true, want: "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s fuzzy=%t", tt.input, tt.fuzzy), func(t *testing.T) {
+			q, err := Parse(tt.input, nil, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.Fuzzy = tt.fuzzy
+			if got := order(Rank(q, rankDocs)); got != tt.want {
+				t.Errorf("Rank() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	parse := func(input string) *Query {
+		q, err := Parse(input, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.Fuzzy = true
+		return q
+	}
+	doc := Document{Command: "kubectl get pods", Description: "List the pods", Tags: []string{"k8s"}}
+
+	// the terms of a clause add up, the best clause counts
+	one, two := parse("kubectl").Score(doc), parse("kubectl pods").Score(doc)
+	if two <= one {
+		t.Errorf("score of two terms %.2f <= score of one %.2f", two, one)
+	}
+	if or := parse("kubectl pods OR kubectl").Score(doc); or != two {
+		t.Errorf("score with OR = %.2f, want the best clause %.2f", or, two)
+	}
+
+	// the start of a word scores as the whole word, better than the middle of a word
+	word, prefix, middle := parse("get").Score(doc), parse("ge").Score(doc), parse("ubec").Score(doc)
+	if !(word == prefix && prefix > middle) {
+		t.Errorf("scores of a word %.2f, its start %.2f and its middle %.2f", word, prefix, middle)
+	}
+	if exact, typo, abbreviation := parse("kubectl").Score(doc), parse("kubectk").Score(doc), parse("kbctl").Score(doc); !(exact > typo && typo > abbreviation && abbreviation > 0) {
+		t.Errorf("scores of the word %.2f, a typo %.2f and an abbreviation %.2f", exact, typo, abbreviation)
+	}
+
+	// negations are never fuzzy
+	if score := parse("kubectl -kubcetl").Score(doc); score == 0 {
+		t.Error("a typo of a negated word excluded the note")
+	}
+	if score := parse("docker").Score(doc); score != 0 {
+		t.Errorf("score of a missing word = %.2f, want 0", score)
+	}
+}
@@ -0,0 +1,4 @@
+package search
+
+// Locations are the note fields that can be searched
+var Locations = []string{"command", "description", "tags"}
@@ -0,0 +1,168 @@
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Finding is a likely secret found in a text
+type Finding struct {
+	// Kind describes the secret, e.g. "AWS access key"
+	Kind  string
+	Value string
+	// Start and End are the byte offsets of the value in the text
+	Start int
+	End   int
+	// Placeholder replaces the value when it's redacted
+	Placeholder string
+}
+
+// Masked returns the start of the value, enough to recognize it without showing it
+func (f Finding) Masked() string {
+	if len(f.Value) <= 8 {
+		return strings.Repeat("*", len(f.Value))
+	}
+	return f.Value[:4] + strings.Repeat("*", 8)
+}
+
+// rule finds a kind of secret, the secret is the first group of the expression or the whole match
+type rule struct {
+	kind        string
+	placeholder string
+	re          *regexp.Regexp
+}
+
+var rules = []rule{
+	{"private key", "<PRIVATE_KEY>", regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY-----(?s:.*?-----END (?:[A-Z0-9]+ )*PRIVATE KEY-----|.*)`)},
+	{"AWS access key", "<AWS_ACCESS_KEY_ID>", regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16})\b`)},
+	{"AWS secret key", "<AWS_SECRET_ACCESS_KEY>", regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`)},
+	{"GitHub token", "<GITHUB_TOKEN>", regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"Slack token", "<SLACK_TOKEN>", regexp.MustCompile(`\b(xox[abposr]-[A-Za-z0-9-]{10,})`)},
+	{"JSON web token", "<TOKEN>", regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*)`)},
+	{"bearer token", "<TOKEN>", regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{8,})`)},
+	{"basic auth credentials", "<CREDENTIALS>", regexp.MustCompile(`(?i)\bbasic\s+([A-Za-z0-9+/]{8,}={0,2})`)},
+	{"password in URL", "<PASSWORD>", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:([^/\s@]+)@`)},
+	{"password", "<PASSWORD>", regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|client[_-]?secret)["']?\s*[=:]\s*["']?([^\s"'&;|,]{4,})`)},
+	{"password", "<PASSWORD>", regexp.MustCompile(`(?i)--(?:password|passwd|pass|token|secret|api-key|client-secret)\s+["']?([^\s"'&;|]{4,})`)},
+	{"password", "<PASSWORD>", regexp.MustCompile(`\bmysql(?:dump|admin)?\b.*?\s-p([^\s-][^\s]*)`)},
+}
+
+// minEntropyLength is the shortest string checked for high entropy
+const minEntropyLength = 20
+
+// randomness is the share of the highest entropy a string of its length can have above which it looks random
+const randomness = 0.85
+
+// maxEntropy caps the entropy required from long strings, in bits per character
+const maxEntropy = 4.3
+
+var words = regexp.MustCompile(`[A-Za-z0-9+/_.-]+=*`)
+
+var hex = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+// camelCase matches the words of identifiers, e.g. Image in SomeImageTag2
+var camelCase = regexp.MustCompile(`[A-Z]?[a-z]{3,}`)
+
+// identifierShare is the share of a word in camelCase words above which it's an identifier, not a key
+const identifierShare = 0.7
+
+// Scan returns the likely secrets of a text, in order of appearance
+func Scan(text string) []Finding {
+	findings := []Finding{}
+	overlaps := func(start, end int) bool {
+		for _, f := range findings {
+			if start < f.End && end > f.Start {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, r := range rules {
+		for _, match := range r.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := match[0], match[1]
+			if len(match) > 2 && match[2] >= 0 {
+				start, end = match[2], match[3]
+			}
+			if isVariable(text[start:end]) || overlaps(start, end) {
+				continue
+			}
+			findings = append(findings, Finding{Kind: r.kind, Value: text[start:end], Start: start, End: end, Placeholder: r.placeholder})
+		}
+	}
+
+	for _, match := range words.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		// flag names, e.g. --saveDevExactVersion9, and variables, e.g. $DEPLOY_TOKEN_v2
+		if strings.HasPrefix(text[start:end], "-") || strings.HasSuffix(text[:start], "$") || strings.HasSuffix(text[:start], "${") {
+			continue
+		}
+		value := strings.Trim(text[start:end], ".-")
+		if !looksRandom(value) || overlaps(start, end) {
+			continue
+		}
+		start = start + strings.Index(text[start:end], value)
+		findings = append(findings, Finding{Kind: "high entropy string", Value: value, Start: start, End: start + len(value), Placeholder: "<SECRET>"})
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Start < findings[j].Start
+	})
+	return findings
+}
+
+// Redact replaces the findings in the text by their placeholders
+func Redact(text string, findings []Finding) string {
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		if f.Start < last {
+			continue
+		}
+		b.WriteString(text[last:f.Start])
+		b.WriteString(f.Placeholder)
+		last = f.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// isVariable reports whether the value refers to a secret instead of holding it,
+// e.g. an environment variable or a placeholder
+func isVariable(value string) bool {
+	return strings.HasPrefix(value, "$") || strings.HasPrefix(value, "<") || strings.HasPrefix(value, "{{") || strings.HasPrefix(value, "%")
+}
+
+// looksRandom reports whether a word is long and random enough to be a key or a token.
+// Hexadecimal strings are left out, they're mostly hashes and commit IDs, and so are words
+// with separators, e.g. paths and branch names such as feature/ABC-1234-someName2, and identifiers.
+func looksRandom(value string) bool {
+	if len(value) < minEntropyLength || hex.MatchString(value) || strings.ContainsAny(value, "/-") {
+		return false
+	}
+	if inWords := len(strings.Join(camelCase.FindAllString(value, -1), "")); float64(inWords) >= identifierShare*float64(len(value)) {
+		return false
+	}
+	if !strings.ContainsAny(value, "0123456789") || strings.ToLower(value) == value || strings.ToUpper(value) == value {
+		return false
+	}
+	return entropy(value) >= math.Min(randomness*math.Log2(float64(len(value))), maxEntropy)
+}
+
+// entropy returns the Shannon entropy of the string in bits per character
+func entropy(value string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range value {
+		counts[r]++
+	}
+
+	e := 0.0
+	n := float64(len(value))
+	for _, count := range counts {
+		p := float64(count) / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
@@ -0,0 +1,344 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/output"
+	"github.com/carloscastrojumo/remindme/pkg/search"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+)
+
+// NoteInput is the body of the requests adding or changing a note
+type NoteInput struct {
+	Tags        []string `json:"tags"`
+	Command     string   `json:"command"`
+	Description string   `json:"description"`
+	Secret      bool     `json:"secret,omitempty"`
+	Shell       string   `json:"shell,omitempty"`
+	// Uses replaces the uses of the note when it's set, e.g. when notes are merged
+	Uses *int `json:"uses,omitempty"`
+}
+
+// GET /api/notes?tag=k8s&reveal=true, or ?command=... for the notes with exactly the command
+func (s *Server) listNotes(w http.ResponseWriter, r *http.Request) error {
+	var notes interface{}
+	var err error
+	if command := r.URL.Query().Get("command"); command != "" {
+		notes, err = s.service.GetByCommand(r.Context(), command)
+	} else if tags := r.URL.Query()["tag"]; len(tags) > 0 {
+		notes, err = s.service.GetByTags(r.Context(), tags)
+	} else {
+		notes, err = s.service.GetAll(r.Context())
+	}
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, output.Notes(notes, reveal(r)))
+	return nil
+}
+
+// GET /api/groups?tag=k8s&q=pods lists the notes grouped by their tags, as 'rmm list' shows them.
+// With q only the notes found are listed, best matches first, and tag keeps the ones with any of the tags.
+func (s *Server) groups(w http.ResponseWriter, r *http.Request) error {
+	params := r.URL.Query()
+	tags := params["tag"]
+
+	var result interface{}
+	var err error
+	switch {
+	case params.Get("q") != "":
+		query, parseErr := search.Parse(params.Get("q"), nil, false)
+		if parseErr != nil {
+			return invalid(fmt.Errorf("invalid query: %w", parseErr))
+		}
+		query.Fuzzy = true
+		result, err = s.service.Search(r.Context(), query)
+	case len(tags) > 0:
+		result, err = s.service.GetByTags(r.Context(), tags)
+	default:
+		result, err = s.service.GetAll(r.Context())
+	}
+	if err != nil {
+		return err
+	}
+
+	notes := output.Notes(result, reveal(r))
+	if params.Get("q") != "" && len(tags) > 0 {
+		filtered := []output.Note{}
+		for _, note := range notes {
+			if hasAnyTag(note.Tags, tags) {
+				filtered = append(filtered, note)
+			}
+		}
+		notes = filtered
+	}
+
+	writeJSON(w, http.StatusOK, output.Groups(notes, true))
+	return nil
+}
+
+// POST /api/notes, a note with the command of another note is refused unless ?duplicate=add adds it
+// anyway or ?duplicate=merge merges it into the other note
+func (s *Server) createNote(w http.ResponseWriter, r *http.Request) error {
+	input, err := decodeNote(r)
+	if err != nil {
+		return err
+	}
+
+	uses := 0
+	if input.Uses != nil {
+		uses = *input.Uses
+	}
+	note := storage.Note{
+		Tags:        input.Tags,
+		Command:     input.Command,
+		Description: input.Description,
+		Uses:        uses,
+		Secret:      input.Secret,
+		Shell:       input.Shell,
+	}
+
+	switch r.URL.Query().Get("duplicate") {
+	case "":
+		note, err = s.service.Create(r.Context(), note)
+	case "add":
+		note, err = s.service.CreateAnyway(r.Context(), note)
+	case "merge":
+		var d *storage.Note
+		if d, err = s.service.FindDuplicate(r.Context(), note.Command); err != nil {
+			return err
+		}
+		if d == nil {
+			note, err = s.service.CreateAnyway(r.Context(), note)
+			break
+		}
+		merged := storage.MergeNotes(*d, note)
+		if err := s.service.Update(r.Context(), merged); err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, output.Notes(merged, reveal(r))[0])
+		return nil
+	default:
+		return invalid(fmt.Errorf("unknown duplicate action %q (add, merge)", r.URL.Query().Get("duplicate")))
+	}
+	if err != nil {
+		return err
+	}
+
+	if note.ID != "" {
+		w.Header().Set("Location", "/api/notes/"+note.ID)
+	}
+	writeJSON(w, http.StatusCreated, output.Notes(note, reveal(r))[0])
+	return nil
+}
+
+// GET /api/notes/{id}, the ID can be the start of an ID matching a single note
+func (s *Server) getNote(w http.ResponseWriter, r *http.Request) error {
+	note, err := s.service.GetNote(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, output.Notes(*note, reveal(r))[0])
+	return nil
+}
+
+// PUT /api/notes/{id} replaces the note, its uses are kept unless they're given.
+// A masked command is ignored, so a secret note read without reveal can be sent back.
+func (s *Server) updateNote(w http.ResponseWriter, r *http.Request) error {
+	input, err := decodeNote(r)
+	if err != nil {
+		return err
+	}
+
+	note, err := s.service.GetNote(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	if !(note.Secret && input.Command == output.SecretMask) {
+		note.Command = input.Command
+	}
+	note.Tags = input.Tags
+	note.Description = input.Description
+	note.Secret = input.Secret
+	note.Shell = input.Shell
+	if input.Uses != nil {
+		note.Uses = *input.Uses
+	}
+	note.Score = 0
+	if err := s.service.Update(r.Context(), *note); err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, output.Notes(*note, reveal(r))[0])
+	return nil
+}
+
+// DELETE /api/notes/{id}
+func (s *Server) deleteNote(w http.ResponseWriter, r *http.Request) error {
+	note, err := s.service.GetNote(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	if err := s.service.Remove(r.Context(), note.ID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// DELETE /api/notes?tag=k8s removes the notes with any of the tags
+func (s *Server) deleteNotesByTags(w http.ResponseWriter, r *http.Request) error {
+	tags := r.URL.Query()["tag"]
+	if len(tags) == 0 {
+		return invalid(errors.New("give the tags of the notes to remove, e.g. ?tag=k8s"))
+	}
+	if err := s.service.RemoveByTags(r.Context(), tags); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// POST /api/notes/{id}/uses counts a use of the note, e.g. when it's copied
+func (s *Server) markUsed(w http.ResponseWriter, r *http.Request) error {
+	note, err := s.service.GetNote(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	if err := s.service.MarkUsed(r.Context(), note.ID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// GET /api/search?q=kubectl+pods&field=command&exact=true&caseSensitive=true
+func (s *Server) search(w http.ResponseWriter, r *http.Request) error {
+	params := r.URL.Query()
+	if params.Get("q") == "" {
+		return invalid(errors.New("the q parameter is required"))
+	}
+
+	for _, field := range params["field"] {
+		if !contains(search.Locations, field) {
+			return invalid(fmt.Errorf("unknown field %q (%s)", field, strings.Join(search.Locations, ", ")))
+		}
+	}
+
+	query, err := search.Parse(params.Get("q"), params["field"], flag(r, "caseSensitive"))
+	if err != nil {
+		return invalid(fmt.Errorf("invalid query: %w", err))
+	}
+	query.Fuzzy = !flag(r, "exact")
+
+	notes, err := s.service.Search(r.Context(), query)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, output.Notes(notes, reveal(r)))
+	return nil
+}
+
+// GET /api/tags
+func (s *Server) tags(w http.ResponseWriter, r *http.Request) error {
+	tags, err := s.service.GetTags(r.Context())
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+	return nil
+}
+
+// GET /api/export?format=ndjson returns every note as a file 'rmm add' reads back
+func (s *Server) export(w http.ResponseWriter, r *http.Request) error {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "ndjson" {
+		return invalid(fmt.Errorf("export format %q not supported (json, ndjson)", format))
+	}
+
+	result, err := s.service.GetAll(r.Context())
+	if err != nil {
+		return err
+	}
+	notes := output.Notes(result, reveal(r))
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="notes.%s"`, format))
+	if format == "json" {
+		writeJSON(w, http.StatusOK, notes)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	for _, note := range notes {
+		encoder.Encode(note)
+	}
+	return nil
+}
+
+// decodeNote reads the note of the request body, a command is required
+func decodeNote(r *http.Request) (NoteInput, error) {
+	input := NoteInput{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return input, invalid(fmt.Errorf("invalid note: %w", err))
+	}
+	if strings.TrimSpace(input.Command) == "" {
+		return input, invalid(errors.New("invalid note: the command is missing"))
+	}
+	if input.Tags == nil {
+		input.Tags = []string{}
+	}
+	return input, nil
+}
+
+// reveal reports whether the commands of secret notes are shown, with ?reveal=true
+func reveal(r *http.Request) bool {
+	return flag(r, "reveal")
+}
+
+// flag returns a boolean query parameter, e.g. ?exact=true or ?exact
+func flag(r *http.Request, name string) bool {
+	if !r.URL.Query().Has(name) {
+		return false
+	}
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return true
+	}
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+func hasAnyTag(noteTags []string, tags []string) bool {
+	for _, tag := range tags {
+		if contains(noteTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+)
+
+// maxBodySize bounds the size of request bodies
+const maxBodySize = 1 << 20
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// Error codes of the API, clients use them to tell errors apart
+const (
+	CodeInvalid      = "invalid"
+	CodeUnauthorized = "unauthorized"
+	CodeNotFound     = "not_found"
+	CodeAmbiguousID  = "ambiguous_id"
+	CodeConflict     = "conflict"
+	CodeDuplicate    = "duplicate"
+	CodeKey          = "key"
+	CodeUnavailable  = "unavailable"
+	CodeInternal     = "internal"
+)
+
+// Error is the body of error responses
+type Error struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// errorCodes map errors to their code and HTTP status, the first one matching is used
+var errorCodes = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{errdefs.ErrUsage, CodeInvalid, http.StatusBadRequest},
+	{errdefs.ErrNotFound, CodeNotFound, http.StatusNotFound},
+	{errdefs.ErrAmbiguousID, CodeAmbiguousID, http.StatusConflict},
+	{errdefs.ErrConflict, CodeConflict, http.StatusConflict},
+	{errdefs.ErrDuplicate, CodeDuplicate, http.StatusConflict},
+	{crypt.ErrNoKey, CodeKey, http.StatusInternalServerError},
+	{crypt.ErrWrongKey, CodeKey, http.StatusInternalServerError},
+	{errdefs.ErrStorageUnavailable, CodeUnavailable, http.StatusServiceUnavailable},
+}
+
+// Server serves the notes of a note service over a REST API, see openapi.yaml
+type Server struct {
+	service *storage.NoteService
+	token   string
+	mux     *http.ServeMux
+	// csrf rejects the cross-origin requests of browsers changing the notes
+	csrf *http.CrossOriginProtection
+	// mu serializes the requests, the storages aren't safe for concurrent use
+	mu sync.Mutex
+}
+
+// New returns a server for the notes of the service, requests must send the token as a bearer token
+func New(service *storage.NoteService, token string) *Server {
+	s := &Server{service: service, token: token, mux: http.NewServeMux(), csrf: http.NewCrossOriginProtection()}
+
+	s.mux.HandleFunc("GET /api/openapi.yaml", s.openAPI)
+	s.handle("GET /api/notes", s.listNotes)
+	s.handle("POST /api/notes", s.createNote)
+	s.handle("DELETE /api/notes", s.deleteNotesByTags)
+	s.handle("GET /api/notes/{id}", s.getNote)
+	s.handle("PUT /api/notes/{id}", s.updateNote)
+	s.handle("DELETE /api/notes/{id}", s.deleteNote)
+	s.handle("POST /api/notes/{id}/uses", s.markUsed)
+	s.handle("GET /api/search", s.search)
+	s.handle("GET /api/tags", s.tags)
+	s.handle("GET /api/groups", s.groups)
+	s.handle("GET /api/export", s.export)
+	return s
+}
+
+// ServeHTTP serves a request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	s.csrf.Handler(s.mux).ServeHTTP(w, r)
+	logger.Debug("%s %s %s", r.Method, r.URL.RequestURI(), time.Since(start).Round(time.Microsecond))
+}
+
+// Handle registers a handler outside of the API, e.g. the web UI. It's served without a token,
+// the pages must send it to the API themselves.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// handle registers an API handler, it checks the token and returns its errors as JSON
+func (s *Server) handle(pattern string, handler func(w http.ResponseWriter, r *http.Request) error) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="remindme"`)
+			writeJSON(w, http.StatusUnauthorized, Error{Error: "missing or wrong token", Code: CodeUnauthorized})
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		err := s.service.Refresh(r.Context())
+		if err == nil {
+			err = handler(w, r)
+		}
+		if err != nil {
+			writeError(w, err)
+		}
+	})
+}
+
+// authorized checks the bearer token of the request, nothing is served without a token
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPI)
+}
+
+// writeJSON writes the value as the JSON body of the response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeError writes the error with its code and HTTP status
+func writeError(w http.ResponseWriter, err error) {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, Error{Error: err.Error(), Code: CodeInvalid})
+		return
+	}
+
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			writeJSON(w, e.status, Error{Error: err.Error(), Code: e.code})
+			return
+		}
+	}
+
+	logger.Error("Error: %s", err)
+	writeJSON(w, http.StatusInternalServerError, Error{Error: err.Error(), Code: CodeInternal})
+}
+
+// invalid returns an error for an invalid request
+func invalid(err error) error {
+	return errdefs.Mark(err, errdefs.ErrUsage)
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/carloscastrojumo/remindme/pkg/output"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+	yaml "github.com/carloscastrojumo/remindme/pkg/storage/yaml"
+)
+
+const testToken = "test-token"
+
+// newServer serves the notes of a YAML storage with the token, data is the content of its data file
+func newServer(t *testing.T, token string, data string) *httptest.Server {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "notes.yaml")
+	if err := os.WriteFile(name, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := yaml.Initialize(&yaml.Config{Name: name, Cipher: crypt.New("test key")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(storage.NewNoteService(store), token))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// call sends a request with the test token and decodes the response into result, it returns the status
+func call(t *testing.T, srv *httptest.Server, method string, path string, body string, result interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, result); err != nil {
+			t.Fatalf("%s %s: %v\n%s", method, path, err, data)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAuthorization(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{name: "token", token: testToken, header: "Bearer " + testToken, want: http.StatusOK},
+		{name: "no header", token: testToken, want: http.StatusUnauthorized},
+		{name: "wrong token", token: testToken, header: "Bearer other", want: http.StatusUnauthorized},
+		{name: "not a bearer token", token: testToken, header: testToken, want: http.StatusUnauthorized},
+		{name: "server without a token", token: "", want: http.StatusUnauthorized},
+		{name: "server without a token and an empty one", token: "", header: "Bearer ", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t, tt.token, "")
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/notes", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a local host isn't enough
+			req.Host = "localhost:8080"
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized {
+				var e Error
+				json.NewDecoder(resp.Body).Decode(&e)
+				if e.Code != CodeUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+					t.Errorf("error = %+v, WWW-Authenticate = %q", e, resp.Header.Get("WWW-Authenticate"))
+				}
+			}
+		})
+	}
+
+	// the API description is public
+	srv := newServer(t, testToken, "")
+	resp, err := srv.Client().Get(srv.URL + "/api/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /api/openapi.yaml = %d", resp.StatusCode)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := newServer(t, testToken, `
+- {id: "123456", command: kubectl get pods}
+- {id: "123789", command: kubectl get nodes}
+`)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing note", http.MethodGet, "/api/notes/99", "", http.StatusNotFound, CodeNotFound},
+		{"ambiguous ID", http.MethodGet, "/api/notes/123", "", http.StatusConflict, CodeAmbiguousID},
+		{"ambiguous ID removed", http.MethodDelete, "/api/notes/12", "", http.StatusConflict, CodeAmbiguousID},
+		{"duplicate", http.MethodPost, "/api/notes", `{"command": "kubectl get pods"}`, http.StatusConflict, CodeDuplicate},
+		{"unknown duplicate action", http.MethodPost, "/api/notes?duplicate=replace", `{"command": "ls"}`, http.StatusBadRequest, CodeInvalid},
+		{"invalid JSON", http.MethodPost, "/api/notes", `{"command": `, http.StatusBadRequest, CodeInvalid},
+		{"missing command", http.MethodPost, "/api/notes", `{"tags": ["k8s"]}`, http.StatusBadRequest, CodeInvalid},
+		{"body too large", http.MethodPost, "/api/notes", `{"command": "` + strings.Repeat("a", maxBodySize) + `"}`, http.StatusRequestEntityTooLarge, CodeInvalid},
+		{"invalid query", http.MethodGet, "/api/search?q=%22pods", "", http.StatusBadRequest, CodeInvalid},
+		{"unknown search field", http.MethodGet, "/api/search?q=pods&field=owner", "", http.StatusBadRequest, CodeInvalid},
+		{"missing tags", http.MethodDelete, "/api/notes", "", http.StatusBadRequest, CodeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e Error
+			if status := call(t, srv, tt.method, tt.path, tt.body, &e); status != tt.wantStatus || e.Code != tt.wantCode {
+				t.Errorf("%s %s = %d %+v, want %d %s", tt.method, tt.path, status, e, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCreateDuplicates(t *testing.T) {
+	srv := newServer(t, testToken, "")
+	var first output.Note
+	call(t, srv, http.MethodPost, "/api/notes", `{"command": "kubectl get pods", "tags": ["k8s"]}`, &first)
+
+	var merged output.Note
+	status := call(t, srv, http.MethodPost, "/api/notes?duplicate=merge", `{"command": "kubectl get pods", "tags": ["pods"], "description": "List the pods"}`, &merged)
+	if status != http.StatusOK || merged.ID != first.ID || strings.Join(merged.Tags, ",") != "k8s,pods" || merged.Description != "List the pods" {
+		t.Errorf("POST ?duplicate=merge = %d %+v, want the note %s merged", status, merged, first.ID)
+	}
+
+	var added output.Note
+	if status := call(t, srv, http.MethodPost, "/api/notes?duplicate=add", `{"command": "kubectl get pods"}`, &added); status != http.StatusCreated || added.ID == first.ID {
+		t.Errorf("POST ?duplicate=add = %d %+v, want a new note", status, added)
+	}
+
+	// a merge without a duplicate adds the note
+	var created output.Note
+	if status := call(t, srv, http.MethodPost, "/api/notes?duplicate=merge", `{"command": "git status"}`, &created); status != http.StatusCreated || created.ID == "" {
+		t.Errorf("POST ?duplicate=merge of a new command = %d %+v", status, created)
+	}
+
+	var notes []output.Note
+	call(t, srv, http.MethodGet, "/api/notes?command=kubectl+get+pods", "", &notes)
+	if len(notes) != 2 {
+		t.Errorf("GET /api/notes?command= = %+v, want the note and its duplicate", notes)
+	}
+}
+
+func TestUpdateKeepsMaskedSecretCommand(t *testing.T) {
+	srv := newServer(t, testToken, "")
+	var note output.Note
+	call(t, srv, http.MethodPost, "/api/notes", `{"command": "psql -p hunter2", "secret": true}`, &note)
+	if note.Command != output.SecretMask {
+		t.Errorf("secret command = %q, want it masked", note.Command)
+	}
+
+	// the note as read without reveal is sent back with another description
+	var updated output.Note
+	body := `{"command": "` + output.SecretMask + `", "description": "Connect", "secret": true}`
+	if status := call(t, srv, http.MethodPut, "/api/notes/"+note.ID, body, &updated); status != http.StatusOK {
+		t.Fatalf("PUT = %d", status)
+	}
+
+	var revealed output.Note
+	call(t, srv, http.MethodGet, "/api/notes/"+note.ID+"?reveal=true", "", &revealed)
+	if revealed.Command != "psql -p hunter2" || revealed.Description != "Connect" {
+		t.Errorf("note = %+v, want the command kept and the description changed", revealed)
+	}
+}
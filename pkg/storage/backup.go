@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	yaml "github.com/carloscastrojumo/remindme/pkg/storage/yaml"
+)
+
+// ErrBackupsNotSupported is returned by the backup methods when the storage doesn't keep backups
+var ErrBackupsNotSupported = errors.New("backups are only supported by the YAML storage")
+
+// Backuper is implemented by storages that keep backups of their data
+type Backuper interface {
+	Backups() ([]yaml.Backup, error)
+	CreateBackup() (yaml.Backup, error)
+	BackupNotes(name string) (interface{}, error)
+	RestoreBackup(name string) error
+}
+
+// Change is a note that changed, Before is its previous version
+type Change struct {
+	Before Note
+	After  Note
+}
+
+// Diff holds the notes added, removed and changed between two versions of the notes
+type Diff struct {
+	Added   []Note
+	Removed []Note
+	Changed []Change
+}
+
+// Empty reports whether nothing changed
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Backups returns the storage backups, oldest first
+func (s *NoteService) Backups() ([]yaml.Backup, error) {
+	backuper, ok := s.store.(Backuper)
+	if !ok {
+		return nil, ErrBackupsNotSupported
+	}
+	return backuper.Backups()
+}
+
+// CreateBackup takes a backup of the notes as they are now
+func (s *NoteService) CreateBackup() (yaml.Backup, error) {
+	backuper, ok := s.store.(Backuper)
+	if !ok {
+		return yaml.Backup{}, ErrBackupsNotSupported
+	}
+	return backuper.CreateBackup()
+}
+
+// RestoreBackup replaces the notes with the ones in a backup
+func (s *NoteService) RestoreBackup(name string) error {
+	backuper, ok := s.store.(Backuper)
+	if !ok {
+		return ErrBackupsNotSupported
+	}
+	return backuper.RestoreBackup(name)
+}
+
+// DiffBackup returns the changes made to the notes since a backup was taken
+func (s *NoteService) DiffBackup(ctx context.Context, name string) (*Diff, error) {
+	backuper, ok := s.store.(Backuper)
+	if !ok {
+		return nil, ErrBackupsNotSupported
+	}
+
+	backupNotes, err := backuper.BackupNotes(name)
+	if err != nil {
+		return nil, err
+	}
+	currentNotes, err := s.store.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := toNotes(backupNotes)
+	if err != nil {
+		return nil, err
+	}
+	after, err := toNotes(currentNotes)
+	if err != nil {
+		return nil, err
+	}
+	return DiffNotes(before, after), nil
+}
+
+// DiffNotes compares two versions of the notes by ID, uses aren't considered a change
+func DiffNotes(before []Note, after []Note) *Diff {
+	diff := &Diff{}
+
+	previous := make(map[string]Note)
+	for _, note := range before {
+		previous[note.ID] = note
+	}
+
+	current := make(map[string]bool)
+	for _, note := range after {
+		current[note.ID] = true
+		old, ok := previous[note.ID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, note)
+		case !sameContent(old, note):
+			diff.Changed = append(diff.Changed, Change{Before: old, After: note})
+		}
+	}
+
+	for _, note := range before {
+		if !current[note.ID] {
+			diff.Removed = append(diff.Removed, note)
+		}
+	}
+
+	return diff
+}
+
+func sameContent(a Note, b Note) bool {
+	return a.Command == b.Command && a.Description == b.Description && slices.Equal(a.Tags, b.Tags)
+}
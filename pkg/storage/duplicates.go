@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/carloscastrojumo/remindme/pkg/duplicate"
+)
+
+// Merger is implemented by storages that can merge notes in a single change
+type Merger interface {
+	// Merge replaces the note with the same ID and deletes the notes with the IDs given
+	Merge(ctx context.Context, item interface{}, ids []string) error
+}
+
+// Duplicate is a note with the same command as another one, or nearly
+type Duplicate struct {
+	Note Note
+	Kind duplicate.Kind
+}
+
+// DuplicateGroup is a group of notes with the same command, or nearly
+type DuplicateGroup struct {
+	Kind  duplicate.Kind
+	Notes []Note
+}
+
+// FindDuplicates returns the notes with the same command, or nearly, closest first
+func FindDuplicates(notes []Note, command string) []Duplicate {
+	duplicates := []Duplicate{}
+	for _, note := range notes {
+		if kind := duplicate.Compare(note.Command, command); kind != duplicate.None {
+			duplicates = append(duplicates, Duplicate{Note: note, Kind: kind})
+		}
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Kind > duplicates[j].Kind
+	})
+	return duplicates
+}
+
+// FindDuplicateGroups returns the groups of notes with the same command, or nearly
+func FindDuplicateGroups(notes []Note) []DuplicateGroup {
+	commands := make([]string, len(notes))
+	for i, note := range notes {
+		commands[i] = note.Command
+	}
+
+	groups := []DuplicateGroup{}
+	for _, group := range duplicate.Groups(commands) {
+		g := DuplicateGroup{Kind: group.Kind}
+		for _, i := range group.Indexes {
+			g.Notes = append(g.Notes, notes[i])
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// MergeNotes merges notes into the first one, whose command is kept: tags are added, the first description
+// and shell set are kept, uses are added up and the note is secret when one of them is
+func MergeNotes(notes ...Note) Note {
+	merged := notes[0]
+	merged.Tags = append([]string{}, merged.Tags...)
+	for _, note := range notes[1:] {
+		merged.Tags = appendMissingTags(merged.Tags, note.Tags)
+		if merged.Description == "" {
+			merged.Description = note.Description
+		}
+		if merged.Shell == "" {
+			merged.Shell = note.Shell
+		}
+		merged.Uses += note.Uses
+		merged.Secret = merged.Secret || note.Secret
+	}
+	return merged
+}
+
+// Notes returns all the notes
+func (s *NoteService) Notes(ctx context.Context) ([]Note, error) {
+	result, err := s.store.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toNotes(result)
+}
+
+// Merge merges the notes into the first one and deletes the others
+func (s *NoteService) Merge(ctx context.Context, notes []Note) error {
+	merged := MergeNotes(notes...)
+	ids := []string{}
+	for _, note := range notes[1:] {
+		ids = append(ids, note.ID)
+	}
+
+	if merger, ok := s.store.(Merger); ok {
+		item, err := s.toStoreNote(merged)
+		if err != nil {
+			return err
+		}
+		return merger.Merge(ctx, item, ids)
+	}
+
+	if err := s.Update(ctx, merged); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Remove(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package storage
+
+import "github.com/carloscastrojumo/remindme/pkg/errdefs"
+
+// Errors returned by the storages, use errors.Is to tell them apart
+var (
+	ErrNotFound           = errdefs.ErrNotFound
+	ErrAmbiguousID        = errdefs.ErrAmbiguousID
+	ErrStorageUnavailable = errdefs.ErrStorageUnavailable
+	ErrConflict           = errdefs.ErrConflict
+	ErrDuplicate          = errdefs.ErrDuplicate
+)
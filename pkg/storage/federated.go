@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source is a note service identified by the name of its profile
+type Source struct {
+	Name    string
+	Service *NoteService
+}
+
+// SourcedNote is a note together with the sources it was found in
+type SourcedNote struct {
+	ID          string
+	Tags        []string
+	Command     string
+	Description string
+	Uses        int
+	Secret      bool
+	Shell       string
+	Score       float64
+	Source      string
+	// Sources lists the note of every source when the command was found in several of them,
+	// the ID and description are the ones of the first source
+	Sources []NoteSource `json:",omitempty"`
+}
+
+// NoteSource is the note with a command in a source, each source has its own ID and description
+type NoteSource struct {
+	Source      string
+	ID          string
+	Description string
+}
+
+// Federate runs the query against every source concurrently and merges the results.
+// Notes with identical commands are returned once, listing the note of every source they were found in.
+// Sources that fail don't prevent the results of the others from being returned.
+func Federate(sources []Source, query func(s *NoteService) (interface{}, error)) ([]SourcedNote, error) {
+	results := make([][]Note, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			result, err := query(source.Service)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", source.Name, err)
+				return
+			}
+			if results[i], err = toNotes(result); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", source.Name, err)
+			}
+		}(i, source)
+	}
+	wg.Wait()
+
+	notes := []SourcedNote{}
+	byCommand := make(map[string]int)
+	for i, result := range results {
+		for _, note := range result {
+			if j, ok := byCommand[note.Command]; ok {
+				if len(notes[j].Sources) == 0 {
+					notes[j].Sources = []NoteSource{{Source: notes[j].Source, ID: notes[j].ID, Description: notes[j].Description}}
+				}
+				notes[j].Sources = append(notes[j].Sources, NoteSource{Source: sources[i].Name, ID: note.ID, Description: note.Description})
+				names := strings.Split(notes[j].Source, ", ")
+				notes[j].Source = strings.Join(appendMissingTags(names, []string{sources[i].Name}), ", ")
+				notes[j].Tags = appendMissingTags(notes[j].Tags, note.Tags)
+				notes[j].Uses += note.Uses
+				notes[j].Score = max(notes[j].Score, note.Score)
+				continue
+			}
+			byCommand[note.Command] = len(notes)
+			notes = append(notes, SourcedNote{
+				ID:          note.ID,
+				Tags:        note.Tags,
+				Command:     note.Command,
+				Description: note.Description,
+				Uses:        note.Uses,
+				Secret:      note.Secret,
+				Shell:       note.Shell,
+				Score:       note.Score,
+				Source:      sources[i].Name,
+			})
+		}
+	}
+
+	// ranked results of every source are interleaved, unranked ones keep their order
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].Score > notes[j].Score
+	})
+
+	return notes, errors.Join(errs...)
+}
+
+// toNotes converts the result of a storage backend to notes
+func toNotes(result interface{}) ([]Note, error) {
+	if result == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []Note
+	if err := json.Unmarshal(data, &notes); err == nil {
+		return notes, nil
+	}
+
+	var note Note
+	if err := json.Unmarshal(data, &note); err != nil {
+		return nil, err
+	}
+	return []Note{note}, nil
+}
+
+// appendMissingTags appends the new tags that aren't in tags yet
+func appendMissingTags(tags []string, newTags []string) []string {
+	for _, newTag := range newTags {
+		found := false
+		for _, tag := range tags {
+			if tag == newTag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, newTag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	yaml "github.com/carloscastrojumo/remindme/pkg/storage/yaml"
+)
+
+func newSource(t *testing.T, name string, notes ...Note) Source {
+	t.Helper()
+	store, err := yaml.Initialize(&yaml.Config{Name: filepath.Join(t.TempDir(), name+".yaml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewNoteService(store)
+	for _, note := range notes {
+		if _, err := service.CreateAnyway(context.Background(), note); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Source{Name: name, Service: service}
+}
+
+func TestFederateKeepsEverySource(t *testing.T) {
+	ctx := context.Background()
+	personal := newSource(t, "personal",
+		Note{Command: "kubectl get pods", Description: "List my pods", Tags: []string{"k8s"}},
+		Note{Command: "git status", Tags: []string{"git"}})
+	team := newSource(t, "team",
+		Note{Command: "kubectl get pods", Description: "List the pods of the cluster", Tags: []string{"kubernetes"}})
+	failing := Source{Name: "broken", Service: nil}
+
+	notes, err := Federate([]Source{personal, team, failing}, func(s *NoteService) (interface{}, error) {
+		if s == nil {
+			return nil, errors.New("unavailable")
+		}
+		return s.GetAll(ctx)
+	})
+	if err == nil {
+		t.Error("Federate() = nil error, want the error of the failing source")
+	}
+	if len(notes) != 2 {
+		t.Fatalf("got %d notes, want the identical commands once", len(notes))
+	}
+
+	merged := notes[0]
+	if merged.Command != "kubectl get pods" || merged.Source != "personal, team" {
+		t.Errorf("merged note = %+v", merged)
+	}
+	if len(merged.Sources) != 2 {
+		t.Fatalf("merged note sources = %+v, want both", merged.Sources)
+	}
+	teamNotes, _ := team.Service.GetAll(ctx)
+	teamNote := teamNotes.([]yaml.Note)[0]
+	want := []NoteSource{
+		{Source: "personal", ID: merged.ID, Description: "List my pods"},
+		{Source: "team", ID: teamNote.ID, Description: "List the pods of the cluster"},
+	}
+	for i := range want {
+		if merged.Sources[i] != want[i] {
+			t.Errorf("source %d = %+v, want %+v", i, merged.Sources[i], want[i])
+		}
+	}
+
+	// notes of a single source don't list it again
+	if notes[1].Source != "personal" || notes[1].Sources != nil {
+		t.Errorf("note of a single source = %+v", notes[1])
+	}
+}
@@ -2,9 +2,19 @@ package mongo
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/search"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -17,106 +27,364 @@ type Note struct {
 	Tags        []string           `bson:"tags"`
 	Command     string             `bson:"command"`
 	Description string             `bson:"description"`
+	Uses        int                `bson:"uses,omitempty"`
+	// Secret notes have their command stored encrypted
+	Secret bool `bson:"secret,omitempty"`
+	// Shell runs the command, e.g. bash or python, the user's shell when it's empty
+	Shell string `bson:"shell,omitempty"`
+	// Score is the search ranking, it's not stored
+	Score float64 `bson:"-"`
 }
 
 // Config struct for storing MongoDB client
 type Config struct {
+	// URI is a full connection string (mongodb:// or mongodb+srv://), it takes precedence over Host and Port
+	URI        string
 	Host       string
 	Port       int
 	Database   string
 	Collection string
+
+	Username      string
+	PasswordEnv   string // name of the environment variable holding the password
+	PasswordFile  string // file holding the password
+	AuthSource    string
+	AuthMechanism string
+	ReplicaSet    string
+
+	TLS                   bool
+	TLSCAFile             string
+	TLSCertificateKeyFile string
+	TLSInsecure           bool
+
+	ConnectTimeout time.Duration
+	Timeout        time.Duration // per operation
+
+	// AutoMigrate creates the search indexes on startup
+	AutoMigrate bool
+
+	// Cipher encrypts the commands of secret notes, it's nil when no key is configured
+	Cipher *crypt.Cipher
 }
 
+const (
+	textIndex    = "rmm_text"
+	tagsIndex    = "rmm_tags"
+	commandIndex = "rmm_command"
+)
+
+// DefaultTimeout is used when no connection or operation timeout is configured
+const DefaultTimeout = 10 * time.Second
+
 // Store struct for storing MongoDB client/collection
 type Store struct {
-	db *mongo.Collection
+	client  *mongo.Client
+	db      *mongo.Collection
+	timeout time.Duration
+	cipher  *crypt.Cipher
 }
 
-// Initialize MongoDB client
-func Initialize(config *Config) *Store {
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://"+config.Host+":"+strconv.Itoa(config.Port)))
+// Initialize MongoDB client, the connection is checked with a ping
+func Initialize(ctx context.Context, config *Config) (*Store, error) {
+	clientOptions, err := clientOptions(config)
 	if err != nil {
-		log.Fatal("MongoDB not running", err)
+		return nil, err
+	}
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, errdefs.Mark(fmt.Errorf("could not connect to MongoDB: %w", err), errdefs.ErrStorageUnavailable)
+	}
+
+	store := &Store{
+		client:  client,
+		db:      client.Database(config.Database).Collection(config.Collection),
+		timeout: timeoutOrDefault(config.Timeout),
+		cipher:  config.Cipher,
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, timeoutOrDefault(config.ConnectTimeout))
+	defer cancel()
+	if err := client.Ping(pingCtx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, errdefs.Mark(fmt.Errorf("could not reach MongoDB: %w", err), errdefs.ErrStorageUnavailable)
+	}
+
+	if config.AutoMigrate {
+		if err := store.Migrate(ctx); err != nil {
+			client.Disconnect(context.Background())
+			return nil, fmt.Errorf("could not create the MongoDB indexes: %w", err)
+		}
+	}
+
+	return store, nil
+}
+
+// Close disconnects the MongoDB client
+func (s *Store) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+func clientOptions(config *Config) (*options.ClientOptions, error) {
+	uri := config.URI
+	if uri == "" {
+		uri = "mongodb://" + config.Host + ":" + strconv.Itoa(config.Port)
+	}
+
+	connectTimeout := timeoutOrDefault(config.ConnectTimeout)
+	clientOptions := options.Client().
+		ApplyURI(uri).
+		SetConnectTimeout(connectTimeout).
+		SetServerSelectionTimeout(connectTimeout)
+
+	if config.Username != "" {
+		password, err := readPassword(config)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetAuth(options.Credential{
+			Username:      config.Username,
+			Password:      password,
+			AuthSource:    config.AuthSource,
+			AuthMechanism: config.AuthMechanism,
+		})
+	}
+
+	if config.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(config.ReplicaSet)
+	}
+
+	if config.TLS || config.TLSCAFile != "" || config.TLSCertificateKeyFile != "" {
+		tlsConfig, err := tlsConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+
+	return clientOptions, clientOptions.Validate()
+}
+
+// readPassword reads the password from the configured environment variable or file
+func readPassword(config *Config) (string, error) {
+	switch {
+	case config.PasswordEnv != "":
+		password, ok := os.LookupEnv(config.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s with the MongoDB password is not set", config.PasswordEnv)
+		}
+		return password, nil
+	case config.PasswordFile != "":
+		data, err := os.ReadFile(config.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("could not read the MongoDB password: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", nil
+}
+
+func tlsConfig(config *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.TLSInsecure}
+
+	if config.TLSCAFile != "" {
+		ca, err := os.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the TLS CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", config.TLSCAFile)
+		}
+	}
+
+	if config.TLSCertificateKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertificateKeyFile, config.TLSCertificateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the TLS certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
-	return &Store{db: client.Database(config.Database).Collection(config.Collection)}
+
+	return tlsConfig, nil
+}
+
+func timeoutOrDefault(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultTimeout
+	}
+	return timeout
+}
+
+// withTimeout bounds an operation with the configured timeout
+func (s *Store) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.timeout)
 }
 
 // Insert a note into MongoDB
-func (s *Store) Insert(item interface{}) error {
-	_, err := s.db.InsertOne(context.Background(), item)
+func (s *Store) Insert(ctx context.Context, item interface{}) error {
+	_, err := s.Create(ctx, item)
 	return err
 }
 
-// Get a note from MongoDB
-func (s *Store) Get(id string) (interface{}, error) {
-	objID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
+// Create inserts a note into MongoDB and returns its ID
+func (s *Store) Create(ctx context.Context, item interface{}) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	note := item.(Note)
+	if note.Secret {
+		command, err := s.cipher.EncryptField(note.Command)
+		if err != nil {
+			return "", fmt.Errorf("cannot add a secret note: %w", err)
+		}
+		note.Command = command
 	}
-	filter := bson.M{"_id": objID}
-	// return s.db.FindOne(context.Background(), filter).DecodeBytes()
-	result := Note{}
-	err = s.db.FindOne(context.Background(), filter).Decode(&result)
-	if err == mongo.ErrNoDocuments {
-		return nil, err
+
+	result, err := s.db.InsertOne(ctx, note)
+	if err != nil {
+		return "", err
 	}
-	return result, nil
+	id, _ := result.InsertedID.(primitive.ObjectID)
+	return id.Hex(), nil
 }
 
-// GetByTags gets notes by tags from MongoDB
-func (s *Store) GetByTags(tags []string) (interface{}, error) {
-	notes := make(map[string]Note)
-	for _, tag := range tags {
-		filter := bson.M{"tags": bson.M{"$in": []string{tag}}}
-		cur, err := s.db.Find(context.Background(), filter)
+// Update replaces the note with the same ID
+func (s *Store) Update(ctx context.Context, item interface{}) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	note := item.(Note)
+	if note.Secret {
+		command, err := s.cipher.EncryptField(note.Command)
 		if err != nil {
-			return nil, err
-		}
-		for cur.Next(context.Background()) {
-			var n Note
-			if err := cur.Decode(&n); err != nil {
-				return nil, err
-			}
-			notes[n.ID.String()] = n
+			return fmt.Errorf("cannot save a secret note: %w", err)
 		}
+		note.Command = command
 	}
 
-	var result []Note
-	for _, v := range notes {
-		result = append(result, v)
+	result, err := s.db.ReplaceOne(ctx, bson.M{"_id": note.ID}, note)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("note %s %w", note.ID.Hex(), errdefs.ErrNotFound)
 	}
+	return nil
+}
 
-	return result, nil
+// decrypt decrypts the command of a secret note, without a key it's left encrypted
+func (s *Store) decrypt(note *Note) error {
+	if !note.Secret || !crypt.IsEncryptedField(note.Command) || s.cipher == nil {
+		return nil
+	}
+
+	command, err := s.cipher.DecryptField(note.Command)
+	if err != nil {
+		return fmt.Errorf("error while decrypting note %s: %w", note.ID.Hex(), err)
+	}
+	note.Command = command
+	return nil
 }
 
-// GetAll gets all notes from MongoDB
-func (s *Store) GetAll() (interface{}, error) {
-	notes := make(map[string]Note)
-	filter := bson.M{}
-	cur, err := s.db.Find(context.Background(), filter)
+// objectID returns the ID of the note with the ID, or of the only note whose ID starts with it
+func (s *Store) objectID(ctx context.Context, id string) (primitive.ObjectID, error) {
+	if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+		return objID, nil
+	}
+	if id == "" || len(id) > 24 || strings.Trim(strings.ToLower(id), "0123456789abcdef") != "" {
+		return primitive.NilObjectID, fmt.Errorf("note %s %w", id, errdefs.ErrNotFound)
+	}
+
+	// IDs are compared as strings, which needs MongoDB 4.2 or later
+	filter := bson.M{"$expr": bson.M{"$regexMatch": bson.M{
+		"input": bson.M{"$toString": "$_id"},
+		"regex": "^" + strings.ToLower(id),
+	}}}
+	cur, err := s.db.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(2))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	defer cur.Close(ctx)
+
+	var found []Note
+	if err := cur.All(ctx, &found); err != nil {
+		return primitive.NilObjectID, err
+	}
+	switch len(found) {
+	case 0:
+		return primitive.NilObjectID, fmt.Errorf("note %s %w", id, errdefs.ErrNotFound)
+	case 1:
+		return found[0].ID, nil
+	}
+	return primitive.NilObjectID, fmt.Errorf("%w %s, it matches several notes", errdefs.ErrAmbiguousID, id)
+}
+
+// Get a note from MongoDB, by its ID or the start of it when only one note matches
+func (s *Store) Get(ctx context.Context, id string) (interface{}, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	objID, err := s.objectID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	for cur.Next(context.Background()) {
-		var n Note
-		if err := cur.Decode(&n); err != nil {
-			return nil, err
+	filter := bson.M{"_id": objID}
+	result := Note{}
+	if err := s.db.FindOne(ctx, filter).Decode(&result); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("note %s %w", id, errdefs.ErrNotFound)
 		}
-		notes[n.ID.String()] = n
+		return nil, err
 	}
+	if err := s.decrypt(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetByTags gets the notes with any of the tags from MongoDB, in the order they were added
+func (s *Store) GetByTags(ctx context.Context, tags []string) (interface{}, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.find(ctx, bson.M{"tags": bson.M{"$in": tags}})
+}
 
-	var result []Note
-	for _, v := range notes {
-		result = append(result, v)
+// GetByCommand returns the notes with the command. The commands of secret notes are encrypted,
+// they're compared once decrypted.
+func (s *Store) GetByCommand(ctx context.Context, command string) (interface{}, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	found, err := s.find(ctx, bson.M{"$or": bson.A{bson.M{"command": command}, bson.M{"secret": true}}})
+	if err != nil {
+		return nil, err
 	}
+	notes := []Note{}
+	for _, note := range found {
+		if note.Command == command {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
 
-	return result, nil
+// GetAll gets all notes from MongoDB, in the order they were added
+func (s *Store) GetAll(ctx context.Context) (interface{}, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.find(ctx, bson.M{})
 }
 
 // GetTags returns all available tags
-func (s *Store) GetTags() ([]string, error) {
+func (s *Store) GetTags(ctx context.Context) ([]string, error) {
 	var tags []string
-	notesInt, _ := s.GetAll()
+	notesInt, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
 	notes, _ := notesInt.([]Note)
 	for _, note := range notes {
 		for _, tag := range note.Tags {
@@ -128,22 +396,53 @@ func (s *Store) GetTags() ([]string, error) {
 	return tags, nil
 }
 
-// Delete a note by ID from MongoDB
-func (s *Store) Delete(id string) error {
-	objID, err := primitive.ObjectIDFromHex(id)
+// IncrementUses counts a use of a note, uses rank search results
+func (s *Store) IncrementUses(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	objID, err := s.objectID(ctx, id)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("note %s %w", id, errdefs.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete a note by ID, or the start of it, from MongoDB
+func (s *Store) Delete(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	objID, err := s.objectID(ctx, id)
 	if err != nil {
 		return err
 	}
 	filter := bson.M{"_id": objID}
-	_, err = s.db.DeleteOne(context.Background(), filter)
-	return err
+	result, err := s.db.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("note %s %w", id, errdefs.ErrNotFound)
+	}
+	return nil
 }
 
 // DeleteByTags deletes notes by tags from MongoDB
-func (s *Store) DeleteByTags(tags []string) error {
+func (s *Store) DeleteByTags(ctx context.Context, tags []string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	for _, tag := range tags {
 		filter := bson.M{"tags": bson.M{"$in": []string{tag}}}
-		_, err := s.db.DeleteMany(context.Background(), filter)
+		_, err := s.db.DeleteMany(ctx, filter)
 		if err != nil {
 			return err
 		}
@@ -152,42 +451,257 @@ func (s *Store) DeleteByTags(tags []string) error {
 	return nil
 }
 
-// Search for notes by tags, description or command from MongoDB
-func (s *Store) Search(searchWords []string, searchLocations []string) (interface{}, error) {
-	notes := make(map[string]Note)
-	filterLocs := []bson.M{}
-	for _, searchLocation := range searchLocations {
-		for _, searchWord := range searchWords {
-			switch searchLocation {
-			case "command":
-				filterLocs = append(filterLocs, bson.M{"command": primitive.Regex{Pattern: searchWord, Options: ""}})
-			case "description":
-				filterLocs = append(filterLocs, bson.M{"description": primitive.Regex{Pattern: searchWord, Options: ""}})
-			case "tags":
-				filterLocs = append(filterLocs, bson.M{"tags": primitive.Regex{Pattern: searchWord, Options: ""}})
+// Search for notes by tags, description or command from MongoDB, best matches first.
+// Queries of plain words in every field run on the text index when it exists, ranked by its relevance score.
+// Other queries, and the ones the text index finds nothing for, are ranked by search.Rank.
+// Fuzzy queries that find nothing are ranked against the notes that may match with typos.
+// The commands of secret notes are encrypted in the database, only the fuzzy ranking searches them.
+func (s *Store) Search(ctx context.Context, q *search.Query) (interface{}, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// the text index matches whole words, fall back to substrings when it finds nothing
+	if words, ok := textSearchWords(q); ok {
+		notes, err := s.textSearch(ctx, words)
+		if err != nil && !isIndexNotFound(err) {
+			return nil, err
+		}
+		if len(notes) > 0 {
+			return notes, nil
+		}
+	}
+
+	notes, err := s.find(ctx, queryFilter(q))
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 && q.Fuzzy {
+		if notes, err = s.find(ctx, fuzzyFilter(q)); err != nil {
+			return nil, err
+		}
+	}
+
+	docs := make([]search.Document, len(notes))
+	for i, note := range notes {
+		docs[i] = search.Document{Command: note.Command, Description: note.Description, Tags: note.Tags, Uses: note.Uses}
+	}
+
+	result := []Note{}
+	for _, ranked := range search.Rank(q, docs) {
+		note := notes[ranked.Index]
+		note.Score = ranked.Score
+		result = append(result, note)
+	}
+	return result, nil
+}
+
+// queryFilter translates a query to a MongoDB filter
+func queryFilter(q *search.Query) bson.M {
+	clauses := bson.A{}
+	for _, clause := range q.Clauses {
+		terms := bson.A{}
+		for _, term := range clause.Terms {
+			terms = append(terms, termFilter(q, term))
+		}
+		clauses = append(clauses, bson.M{"$and": terms})
+	}
+	return bson.M{"$or": clauses}
+}
+
+func termFilter(q *search.Query, term search.Term) bson.M {
+	pattern := regexp.QuoteMeta(term.Value)
+	if term.Regex != nil {
+		// the compiled expression holds the case insensitive flag
+		pattern = term.Regex.String()
+	} else if !q.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	fields := bson.A{}
+	for _, field := range term.Fields {
+		fields = append(fields, bson.M{field: primitive.Regex{Pattern: pattern, Options: ""}})
+	}
+
+	filter := bson.M{"$or": fields}
+	if term.Negate {
+		return bson.M{"$nor": bson.A{filter}}
+	}
+	return filter
+}
+
+// fuzzyFilter selects the notes a fuzzy query may match, so they're ranked without reading every note.
+// A word matches with typos when one of the pieces search.Rank allows an edit in for is found, and as
+// an abbreviation when its characters are found in order. Secret notes are always ranked, their
+// commands are encrypted in the database.
+func fuzzyFilter(q *search.Query) bson.M {
+	clauses := bson.A{}
+	for _, clause := range q.Clauses {
+		terms := bson.A{}
+		for _, term := range clause.Terms {
+			// negations never match with typos, they only exclude notes once ranked
+			if term.Negate {
+				continue
+			}
+			terms = append(terms, fuzzyTermFilter(q, term))
+		}
+		if len(terms) == 0 {
+			return bson.M{}
+		}
+		clauses = append(clauses, bson.M{"$and": terms})
+	}
+	return bson.M{"$or": append(clauses, bson.M{"secret": true})}
+}
+
+func fuzzyTermFilter(q *search.Query, term search.Term) bson.M {
+	word := []rune(term.Value)
+	if term.Regex != nil || strings.ContainsRune(term.Value, ' ') || len(word) < 3 {
+		return termFilter(q, term)
+	}
+
+	// an edit distance of one for every four characters leaves one of distance+1 pieces intact
+	pieces := []string{}
+	if distance := len(word) / 4; distance > 0 {
+		size := len(word) / (distance + 1)
+		for i := 0; i <= distance; i++ {
+			end := (i + 1) * size
+			if i == distance {
+				end = len(word)
 			}
+			pieces = append(pieces, regexp.QuoteMeta(string(word[i*size:end])))
 		}
 	}
 
-	filter := bson.M{"$or": filterLocs}
-	cur, err := s.db.Find(context.Background(), filter)
+	characters := []string{}
+	for _, r := range word {
+		characters = append(characters, regexp.QuoteMeta(string(r)))
+	}
+	pieces = append(pieces, strings.Join(characters, ".*"))
+
+	pattern := strings.Join(pieces, "|")
+	if !q.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	fields := bson.A{}
+	for _, field := range term.Fields {
+		fields = append(fields, bson.M{field: primitive.Regex{Pattern: pattern, Options: "s"}})
+	}
+	return bson.M{"$or": fields}
+}
+
+// textSearchWords returns the words of a query that can be run on the text index:
+// a single clause of plain, case insensitive words searched in every field
+func textSearchWords(q *search.Query) ([]string, bool) {
+	if len(q.Clauses) != 1 || q.CaseSensitive {
+		return nil, false
+	}
+
+	words := []string{}
+	for _, term := range q.Clauses[0].Terms {
+		if term.Regex != nil || term.Negate || len(term.Fields) != len(search.Locations) {
+			return nil, false
+		}
+		words = append(words, term.Value)
+	}
+	return words, true
+}
+
+// scoredNote is a note found by the text index, with the relevance score of the index
+type scoredNote struct {
+	Note      `bson:",inline"`
+	TextScore float64 `bson:"score"`
+}
+
+// textSearch searches the text index, the notes are sorted by the relevance score of the index
+func (s *Store) textSearch(ctx context.Context, words []string) ([]Note, error) {
+	// quoted words are phrases, which are all required
+	phrases := []string{}
+	for _, word := range words {
+		phrases = append(phrases, strconv.Quote(word))
+	}
+	filter := bson.M{"$text": bson.M{"$search": strings.Join(phrases, " ")}}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}})
+
+	cur, err := s.db.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
-	for cur.Next(context.Background()) {
-		var n Note
+	defer cur.Close(ctx)
+
+	result := []Note{}
+	for cur.Next(ctx) {
+		var n scoredNote
 		if err := cur.Decode(&n); err != nil {
 			return nil, err
 		}
-		notes[n.ID.String()] = n
+		if err := s.decrypt(&n.Note); err != nil {
+			return nil, err
+		}
+		n.Note.Score = n.TextScore
+		result = append(result, n.Note)
 	}
+	return result, cur.Err()
+}
 
-	var result []Note
-	for _, v := range notes {
-		result = append(result, v)
+// find returns the notes matching the filter, sorted by ID so they're listed in a stable order:
+// the order they were added, as ObjectIDs start with their creation time
+func (s *Store) find(ctx context.Context, filter interface{}) ([]Note, error) {
+	cur, err := s.db.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
 	}
+	defer cur.Close(ctx)
 
-	return result, nil
+	result := []Note{}
+	for cur.Next(ctx) {
+		var n Note
+		if err := cur.Decode(&n); err != nil {
+			return nil, err
+		}
+		if err := s.decrypt(&n); err != nil {
+			return nil, err
+		}
+		result = append(result, n)
+	}
+	return result, cur.Err()
+}
+
+// Migrate creates the indexes used by searches, it can be run any number of times
+func (s *Store) Migrate(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "command", Value: "text"}, {Key: "description", Value: "text"}, {Key: "tags", Value: "text"}},
+			Options: options.Index().
+				SetName(textIndex).
+				SetWeights(bson.D{{Key: "command", Value: 10}, {Key: "tags", Value: 5}, {Key: "description", Value: 1}}).
+				SetDefaultLanguage("none"),
+		},
+		{
+			Keys:    bson.D{{Key: "tags", Value: 1}},
+			Options: options.Index().SetName(tagsIndex),
+		},
+		{
+			Keys:    bson.D{{Key: "command", Value: 1}, {Key: "secret", Value: 1}},
+			Options: options.Index().SetName(commandIndex),
+		},
+	})
+	return err
+}
+
+// isIndexNotFound reports whether the error is due to the text index not existing
+func isIndexNotFound(err error) bool {
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) {
+		// IndexNotFound
+		return commandErr.Code == 27
+	}
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(27)
 }
 
 func containsTag(tags []string, tag string) bool {
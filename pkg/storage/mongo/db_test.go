@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/carloscastrojumo/remindme/pkg/search"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestFuzzyFilterKeepsFuzzyMatches checks the notes search.Rank matches with typos and abbreviations
+// are selected by the filter of the fuzzy search
+func TestFuzzyFilterKeepsFuzzyMatches(t *testing.T) {
+	tests := []struct {
+		query   string
+		command string
+	}{
+		{"kubctl", "kubectl get pods"},
+		{"kubeclt", "kubectl get pods"},
+		{"kgp", "kubectl get pods"},
+		{"KGP", "kubectl\nget pods"},
+		{"dockr", "docker ps -a"},
+		{"terrafrom", "terraform plan"},
+		{"gst", "git status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := search.Parse(tt.query, []string{"command"}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.Fuzzy = true
+			if len(search.Rank(q, []search.Document{{Command: tt.command}})) == 0 {
+				t.Fatalf("%q doesn't match %q", tt.query, tt.command)
+			}
+
+			term := fuzzyTermFilter(q, q.Clauses[0].Terms[0])
+			pattern := term["$or"].(bson.A)[0].(bson.M)["command"].(primitive.Regex).Pattern
+			if !regexp.MustCompile("(?s)" + pattern).MatchString(tt.command) {
+				t.Errorf("filter %q doesn't select %q", pattern, tt.command)
+			}
+		})
+	}
+}
+
+func TestFuzzyFilterRanksSecretNotes(t *testing.T) {
+	q, err := search.Parse("kgp -prod", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clauses := fuzzyFilter(q)["$or"].(bson.A)
+	if last := clauses[len(clauses)-1]; last.(bson.M)["secret"] != true {
+		t.Errorf("last clause = %v, want secret notes", last)
+	}
+	if terms := clauses[0].(bson.M)["$and"].(bson.A); len(terms) != 1 {
+		t.Errorf("got %d terms, want the negation left to the ranking", len(terms))
+	}
+}
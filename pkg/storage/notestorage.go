@@ -1,24 +1,53 @@
 package storage
 
 import (
+	"context"
 	"errors"
-	"strings"
+	"fmt"
 
+	"github.com/carloscastrojumo/remindme/pkg/duplicate"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/search"
 	mongo "github.com/carloscastrojumo/remindme/pkg/storage/mongo"
+	"github.com/carloscastrojumo/remindme/pkg/storage/remote"
 	yaml "github.com/carloscastrojumo/remindme/pkg/storage/yaml"
-	"github.com/fatih/color"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // NoteStorage is the interface that wraps the basic storage methods.
 type NoteStorage interface {
-	Insert(item interface{}) error
-	Get(id string) (interface{}, error)
-	GetByTags(tags []string) (interface{}, error)
-	GetAll() (interface{}, error)
-	GetTags() ([]string, error)
-	Delete(id string) error
-	DeleteByTags(tags []string) error
-	Search(searchWords []string, searchLocations []string) (interface{}, error)
+	Insert(ctx context.Context, item interface{}) error
+	Update(ctx context.Context, item interface{}) error
+	Get(ctx context.Context, id string) (interface{}, error)
+	GetByTags(ctx context.Context, tags []string) (interface{}, error)
+	GetAll(ctx context.Context) (interface{}, error)
+	GetTags(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByTags(ctx context.Context, tags []string) error
+	Search(ctx context.Context, q *search.Query) (interface{}, error)
+	IncrementUses(ctx context.Context, id string) error
+	Close(ctx context.Context) error
+}
+
+// Migrator is implemented by storages that need to prepare their data, e.g. create indexes
+type Migrator interface {
+	Migrate(ctx context.Context) error
+}
+
+// Creator is implemented by storages that return the ID of the notes they add
+type Creator interface {
+	Create(ctx context.Context, item interface{}) (string, error)
+}
+
+// Refresher is implemented by storages caching the notes, to read them again when they changed
+type Refresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// CommandFinder is implemented by storages that look notes up by their exact command, e.g. to find duplicates
+type CommandFinder interface {
+	GetByCommand(ctx context.Context, command string) (interface{}, error)
 }
 
 // NoteService is the service that handles the storage
@@ -38,21 +67,23 @@ type Note struct {
 	Tags        []string
 	Command     string
 	Description string
+	Uses        int
+	Secret      bool
+	Shell       string
+	Score       float64
 }
 
-var storageType string
-
-// GetStorage returns the storage type
-func GetStorage(config *Config) NoteStorage {
+// GetStorage returns the storage for the storage type
+func GetStorage(ctx context.Context, config *Config) (NoteStorage, error) {
 	switch config.StorageType {
 	case "yaml":
-		storageType = "yaml"
 		return yaml.Initialize(config.StorageConfig.(*yaml.Config))
 	case "mongo":
-		storageType = "mongo"
-		return mongo.Initialize(config.StorageConfig.(*mongo.Config))
+		return mongo.Initialize(ctx, config.StorageConfig.(*mongo.Config))
+	case "http":
+		return remote.Initialize(config.StorageConfig.(*remote.Config))
 	}
-	return nil
+	return nil, fmt.Errorf("storage type %q not supported", config.StorageType)
 }
 
 // NewNoteService returns a new note service
@@ -60,58 +91,232 @@ func NewNoteService(store NoteStorage) *NoteService {
 	return &NoteService{store: store}
 }
 
-// Add adds a new note
-func (s *NoteService) Add(note interface{}) error {
-	switch storageType {
-	case "yaml":
-		return s.store.Insert(yaml.Note{
-			Tags:        note.(Note).Tags,
-			Command:     note.(Note).Command,
-			Description: note.(Note).Description,
-		})
-	case "mongo":
-		return s.store.Insert(mongo.Note{
-			Tags:        note.(Note).Tags,
-			Command:     note.(Note).Command,
-			Description: note.(Note).Description,
-		})
+// Add adds a new note, it fails with ErrDuplicate when another note has the same command
+func (s *NoteService) Add(ctx context.Context, note interface{}) error {
+	_, err := s.Create(ctx, note.(Note))
+	return err
+}
+
+// Create adds a new note and returns it with its ID, the ID is empty when the storage doesn't return it.
+// It fails with ErrDuplicate when another note has the same command, see CreateAnyway.
+func (s *NoteService) Create(ctx context.Context, note Note) (Note, error) {
+	if d, err := s.FindDuplicate(ctx, note.Command); err != nil || d != nil {
+		if d != nil {
+			err = errdefs.Mark(fmt.Errorf("the command is already in note %s", d.ID), ErrDuplicate)
+		}
+		return note, err
+	}
+	return s.CreateAnyway(ctx, note)
+}
+
+// FindDuplicate returns the note with the command, nil when there's none.
+// Near-duplicates, e.g. with other spacing, are different commands.
+func (s *NoteService) FindDuplicate(ctx context.Context, command string) (*Note, error) {
+	notes, err := s.GetByCommand(ctx, command)
+	if err != nil || len(notes) == 0 {
+		return nil, err
+	}
+	return &notes[0], nil
+}
+
+// GetByCommand returns the notes with exactly the command, the storage looks them up when it can
+func (s *NoteService) GetByCommand(ctx context.Context, command string) ([]Note, error) {
+	finder, ok := s.store.(CommandFinder)
+	if !ok {
+		notes, err := s.Notes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		found := []Note{}
+		for _, d := range FindDuplicates(notes, command) {
+			if d.Kind == duplicate.Exact {
+				found = append(found, d.Note)
+			}
+		}
+		return found, nil
+	}
+
+	result, err := finder.GetByCommand(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+	return toNotes(result)
+}
+
+// CreateAnyway adds a new note like Create, even when another note has the same command,
+// e.g. once the user chose to
+func (s *NoteService) CreateAnyway(ctx context.Context, note Note) (Note, error) {
+	item, err := s.toStoreNote(note)
+	if err != nil {
+		return note, err
+	}
+
+	creator, ok := s.store.(Creator)
+	if !ok {
+		return note, s.store.Insert(ctx, item)
+	}
+	note.ID, err = creator.Create(ctx, item)
+	return note, err
+}
+
+// Update replaces the note with the same ID
+func (s *NoteService) Update(ctx context.Context, note Note) error {
+	item, err := s.toStoreNote(note)
+	if err != nil {
+		return err
+	}
+	return s.store.Update(ctx, item)
+}
+
+// toStoreNote converts a note to the note type of the storage
+func (s *NoteService) toStoreNote(note Note) (interface{}, error) {
+	switch s.store.(type) {
+	case *yaml.Yaml:
+		return toYamlNote(note), nil
+	case *mongo.Store:
+		n := mongo.Note{
+			Tags:        note.Tags,
+			Command:     note.Command,
+			Description: note.Description,
+			Uses:        note.Uses,
+			Secret:      note.Secret,
+			Shell:       note.Shell,
+		}
+		if note.ID != "" {
+			id, err := primitive.ObjectIDFromHex(note.ID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid note ID %s: %w", note.ID, err)
+			}
+			n.ID = id
+		}
+		return n, nil
+	case *remote.Store:
+		return remote.Note{
+			ID:          note.ID,
+			Tags:        note.Tags,
+			Command:     note.Command,
+			Description: note.Description,
+			Uses:        note.Uses,
+			Secret:      note.Secret,
+			Shell:       note.Shell,
+		}, nil
+	}
+	return nil, errors.New("storage type not supported")
+}
+
+// AddAll adds several notes, it returns an error for each note, nil for the ones added.
+// The YAML storage adds them with a single write, other storages one by one.
+// Duplicates aren't checked, the caller resolves them as 'rmm add' does.
+func (s *NoteService) AddAll(ctx context.Context, notes []Note) []error {
+	errs := make([]error, len(notes))
+
+	if store, ok := s.store.(*yaml.Yaml); ok {
+		yamlNotes := make([]yaml.Note, len(notes))
+		for i, note := range notes {
+			yamlNotes[i] = toYamlNote(note)
+		}
+		if err := store.InsertAll(ctx, yamlNotes); err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+		}
+		return errs
+	}
+
+	for i, note := range notes {
+		_, errs[i] = s.CreateAnyway(ctx, note)
+	}
+	return errs
+}
+
+func toYamlNote(note Note) yaml.Note {
+	return yaml.Note{
+		ID:          note.ID,
+		Tags:        note.Tags,
+		Command:     note.Command,
+		Description: note.Description,
+		Uses:        note.Uses,
+		Secret:      note.Secret,
+		Shell:       note.Shell,
 	}
-	return errors.New("storage type not supported")
 }
 
 // Get returns a note by id
-func (s *NoteService) Get(id string) (interface{}, error) {
-	return s.store.Get(id)
+func (s *NoteService) Get(ctx context.Context, id string) (interface{}, error) {
+	return s.store.Get(ctx, id)
+}
+
+// GetNote returns a note by id, or by the start of its id when only one note matches
+func (s *NoteService) GetNote(ctx context.Context, id string) (*Note, error) {
+	result, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := toNotes(result)
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, fmt.Errorf("note %s %w", id, ErrNotFound)
+	}
+	return &notes[0], nil
 }
 
 // GetByTags returns all the notes that match the tags
-func (s *NoteService) GetByTags(tags []string) (interface{}, error) {
-	return s.store.GetByTags(tags)
+func (s *NoteService) GetByTags(ctx context.Context, tags []string) (interface{}, error) {
+	return s.store.GetByTags(ctx, tags)
 }
 
 // GetAll returns all the notes
-func (s *NoteService) GetAll() (interface{}, error) {
-	return s.store.GetAll()
+func (s *NoteService) GetAll(ctx context.Context) (interface{}, error) {
+	return s.store.GetAll(ctx)
 }
 
 // GetTags returns all available tags
-func (s *NoteService) GetTags() ([]string, error) {
-	return s.store.GetTags()
+func (s *NoteService) GetTags(ctx context.Context) ([]string, error) {
+	return s.store.GetTags(ctx)
 }
 
 // Remove removes a note by id
-func (s *NoteService) Remove(id string) error {
-	return s.store.Delete(id)
+func (s *NoteService) Remove(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, id)
 }
 
 // RemoveByTags removes all the notes that match the tags
-func (s *NoteService) RemoveByTags(tags []string) error {
-	return s.store.DeleteByTags(tags)
+func (s *NoteService) RemoveByTags(ctx context.Context, tags []string) error {
+	return s.store.DeleteByTags(ctx, tags)
+}
+
+// MarkUsed counts a use of a note, e.g. when it's copied, to rank it higher in searches
+func (s *NoteService) MarkUsed(ctx context.Context, id string) error {
+	return s.store.IncrementUses(ctx, id)
+}
+
+// Refresh reads the notes again when the storage caches them and they changed, e.g. for a long running server
+func (s *NoteService) Refresh(ctx context.Context) error {
+	if refresher, ok := s.store.(Refresher); ok {
+		return refresher.Refresh(ctx)
+	}
+	return nil
+}
+
+// Close closes the underlying storage
+func (s *NoteService) Close(ctx context.Context) error {
+	return s.store.Close(ctx)
 }
 
-// Search returns all the notes that match the search words
-func (s *NoteService) Search(searchWords []string, searchLocations []string) (interface{}, error) {
-	color.Blue("Searching: %s\n", color.GreenString(strings.Join(searchWords, " ")))
-	color.Blue("In: %s\n", color.GreenString(strings.Join(searchLocations, " ")))
-	return s.store.Search(searchWords, searchLocations)
+// Search returns all the notes that match the query
+func (s *NoteService) Search(ctx context.Context, q *search.Query) (interface{}, error) {
+	logger.Debug("Searching: %s", q.Input)
+	return s.store.Search(ctx, q)
+}
+
+// Migrate prepares the storage data, it returns false when the storage has nothing to migrate
+func (s *NoteService) Migrate(ctx context.Context) (bool, error) {
+	migrator, ok := s.store.(Migrator)
+	if !ok {
+		return false, nil
+	}
+	return true, migrator.Migrate(ctx)
 }
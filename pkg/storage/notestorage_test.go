@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	yaml "github.com/carloscastrojumo/remindme/pkg/storage/yaml"
+)
+
+func TestCreateRefusesDuplicates(t *testing.T) {
+	ctx := context.Background()
+	store, err := yaml.Initialize(&yaml.Config{Name: filepath.Join(t.TempDir(), "notes.yaml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewNoteService(store)
+
+	first, err := service.Create(ctx, Note{Command: "kubectl get pods", Tags: []string{"k8s"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Create(ctx, Note{Command: "kubectl get pods", Tags: []string{"pods"}}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Create() of a duplicate = %v, want ErrDuplicate", err)
+	}
+	if err := service.Add(ctx, Note{Command: "kubectl get pods"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Add() of a duplicate = %v, want ErrDuplicate", err)
+	}
+	// near-duplicates are different commands
+	if _, err := service.Create(ctx, Note{Command: "kubectl  get pods"}); err != nil {
+		t.Errorf("Create() of a near-duplicate = %v", err)
+	}
+	if _, err := service.CreateAnyway(ctx, Note{Command: "kubectl get pods"}); err != nil {
+		t.Errorf("CreateAnyway() = %v", err)
+	}
+
+	d, err := service.FindDuplicate(ctx, "kubectl get pods")
+	if err != nil || d == nil || d.ID != first.ID {
+		t.Errorf("FindDuplicate() = %v, %v, want note %s", d, err, first.ID)
+	}
+	notes, err := service.Notes(ctx)
+	if err != nil || len(notes) != 3 {
+		t.Errorf("got %d notes, %v, want 3", len(notes), err)
+	}
+	byCommand, err := service.GetByCommand(ctx, "kubectl get pods")
+	if err != nil || len(byCommand) != 2 || byCommand[0].ID != first.ID {
+		t.Errorf("GetByCommand() = %v, %v, want the note and its duplicate", byCommand, err)
+	}
+}
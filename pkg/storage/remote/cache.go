@@ -0,0 +1,209 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/search"
+)
+
+// secretMask replaces the commands of secret notes in the cache, they aren't written to disk in clear
+const secretMask = "********"
+
+// cache is the content of the cache file
+type cache struct {
+	Time  time.Time `json:"time"`
+	Notes []Note    `json:"notes"`
+}
+
+// saveCache writes the notes to the cache file, the cache is only a copy so errors are ignored
+func (s *Store) saveCache(notes []Note) {
+	if s.config.Cache == "" {
+		return
+	}
+
+	cached := cache{Time: time.Now(), Notes: make([]Note, len(notes))}
+	for i, note := range notes {
+		if note.Secret {
+			note.Command = secretMask
+		}
+		cached.Notes[i] = note
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.config.Cache), 0700); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.config.Cache), ".rmm-cache-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	f.Close()
+	if err != nil || os.Rename(f.Name(), s.config.Cache) != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// loadCache reads the cache file
+func (s *Store) loadCache() (*cache, error) {
+	if s.config.Cache == "" {
+		return nil, errors.New("no cache")
+	}
+	data, err := os.ReadFile(s.config.Cache)
+	if err != nil {
+		return nil, err
+	}
+	cached := &cache{}
+	if err := json.Unmarshal(data, cached); err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+// cacheTime returns when the notes were cached, empty when they aren't
+func (s *Store) cacheTime() string {
+	cached, err := s.loadCache()
+	if err != nil {
+		return ""
+	}
+	return cached.Time.Local().Format("2006-01-02 15:04:05")
+}
+
+func (s *Store) cachedNotes() ([]Note, error) {
+	cached, err := s.loadCache()
+	if err != nil {
+		return nil, errdefs.Mark(fmt.Errorf("error while reading cached notes: %w", err), errdefs.ErrStorageUnavailable)
+	}
+	return cached.Notes, nil
+}
+
+func (s *Store) cachedNote(id string) (interface{}, error) {
+	notes, err := s.cachedNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	found := []Note{}
+	for _, note := range notes {
+		if note.ID == id {
+			return note, nil
+		}
+		if strings.HasPrefix(note.ID, id) {
+			found = append(found, note)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("note %s %w", id, errdefs.ErrNotFound)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("%w %s, it matches %d notes", errdefs.ErrAmbiguousID, id, len(found))
+}
+
+func (s *Store) cachedNotesByTags(tags []string) (interface{}, error) {
+	notes, err := s.cachedNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []Note{}
+	for _, note := range notes {
+		if hasAnyTag(note, tags) {
+			filtered = append(filtered, note)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *Store) cachedNotesByCommand(command string) (interface{}, error) {
+	notes, err := s.cachedNotes()
+	if err != nil {
+		return nil, err
+	}
+	return withCommand(notes, command), nil
+}
+
+// withCommand returns the notes with the command, servers without the command parameter return all notes
+func withCommand(notes []Note, command string) []Note {
+	filtered := []Note{}
+	for _, note := range notes {
+		if note.Command == command {
+			filtered = append(filtered, note)
+		}
+	}
+	return filtered
+}
+
+func (s *Store) cachedTags() ([]string, error) {
+	notes, err := s.cachedNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	for _, note := range notes {
+		for _, tag := range note.Tags {
+			if !contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// cachedSearch ranks the cached notes, exact matches first and fuzzy ones when nothing matches exactly
+func (s *Store) cachedSearch(q *search.Query) (interface{}, error) {
+	notes, err := s.cachedNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]search.Document, len(notes))
+	for i, note := range notes {
+		docs[i] = search.Document{Command: note.Command, Description: note.Description, Tags: note.Tags, Uses: note.Uses}
+	}
+
+	exact := *q
+	exact.Fuzzy = false
+	ranked := search.Rank(&exact, docs)
+	if len(ranked) == 0 && q.Fuzzy {
+		ranked = search.Rank(q, docs)
+	}
+
+	found := []Note{}
+	for _, r := range ranked {
+		note := notes[r.Index]
+		note.Score = r.Score
+		found = append(found, note)
+	}
+	return found, nil
+}
+
+func hasAnyTag(note Note, tags []string) bool {
+	for _, tag := range tags {
+		if contains(note.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,355 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/search"
+)
+
+const (
+	// DefaultTimeout is the time given to each request
+	DefaultTimeout = 10 * time.Second
+	// DefaultRetries is how many times requests that can be repeated are retried
+	DefaultRetries = 2
+)
+
+// retryDelay is the delay before the first retry, it doubles on each retry
+var retryDelay = 200 * time.Millisecond
+
+// Note is a note as sent by the API of rmm serve
+type Note struct {
+	ID          string   `json:"id"`
+	Tags        []string `json:"tags"`
+	Command     string   `json:"command"`
+	Description string   `json:"description"`
+	Uses        int      `json:"uses,omitempty"`
+	Secret      bool     `json:"secret,omitempty"`
+	Shell       string   `json:"shell,omitempty"`
+	Score       float64  `json:"score,omitempty"`
+}
+
+// Config is the configuration of the remote storage
+type Config struct {
+	// URL of the server, e.g. http://notes.internal:8080
+	URL       string
+	TokenEnv  string // name of the environment variable holding the token
+	TokenFile string // file holding the token
+
+	Timeout time.Duration // per request
+	Retries int
+
+	// Cache is the file keeping the notes last read, they're read from it when the server can't be reached.
+	// The notes aren't cached when it's empty.
+	Cache string
+}
+
+// Store is a storage reaching the notes through the API of rmm serve
+type Store struct {
+	config Config
+	client *http.Client
+	token  string
+	// offline is set once the notes are read from the cache
+	offline bool
+}
+
+// apiError is the body of the error responses
+type apiError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// errorCodes map the error codes of the API to the errors of the storages
+var errorCodes = map[string]error{
+	"invalid":      errdefs.ErrUsage,
+	"not_found":    errdefs.ErrNotFound,
+	"ambiguous_id": errdefs.ErrAmbiguousID,
+	"conflict":     errdefs.ErrConflict,
+	"duplicate":    errdefs.ErrDuplicate,
+	"unavailable":  errdefs.ErrStorageUnavailable,
+}
+
+// Initialize the remote storage, the server is only reached by the first request
+func Initialize(config *Config) (*Store, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q, e.g. http://notes.internal:8080", config.URL)
+	}
+
+	token, err := readToken(config)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &Store{config: *config, token: token}
+	store.config.URL = strings.TrimRight(config.URL, "/")
+	if store.config.Timeout <= 0 {
+		store.config.Timeout = DefaultTimeout
+	}
+	if store.config.Retries < 0 {
+		store.config.Retries = 0
+	}
+	store.client = &http.Client{Timeout: store.config.Timeout}
+	return store, nil
+}
+
+// readToken reads the token from the configured environment variable or file
+func readToken(config *Config) (string, error) {
+	switch {
+	case config.TokenEnv != "":
+		token, ok := os.LookupEnv(config.TokenEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s with the server token is not set", config.TokenEnv)
+		}
+		return token, nil
+	case config.TokenFile != "":
+		data, err := os.ReadFile(config.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("could not read the server token: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", nil
+}
+
+// Close releases the remote storage
+func (s *Store) Close(ctx context.Context) error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// Insert adds a note
+func (s *Store) Insert(ctx context.Context, item interface{}) error {
+	_, err := s.Create(ctx, item)
+	return err
+}
+
+// Create adds a note and returns its ID, duplicates are checked by the note service before
+func (s *Store) Create(ctx context.Context, item interface{}) (string, error) {
+	created := Note{}
+	if err := s.do(ctx, http.MethodPost, "/api/notes", url.Values{"duplicate": {"add"}}, item.(Note), &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// Update replaces the note with the same ID
+func (s *Store) Update(ctx context.Context, item interface{}) error {
+	note := item.(Note)
+	return s.do(ctx, http.MethodPut, "/api/notes/"+url.PathEscape(note.ID), nil, note, nil)
+}
+
+// Get returns a note by id, or by the start of its id when only one note matches
+func (s *Store) Get(ctx context.Context, id string) (interface{}, error) {
+	note := Note{}
+	err := s.read(ctx, "/api/notes/"+url.PathEscape(id), url.Values{"reveal": {"true"}}, &note)
+	if s.offline {
+		return s.cachedNote(id)
+	}
+	return note, err
+}
+
+// GetByTags returns the notes with any of the tags
+func (s *Store) GetByTags(ctx context.Context, tags []string) (interface{}, error) {
+	notes := []Note{}
+	err := s.read(ctx, "/api/notes", url.Values{"tag": tags, "reveal": {"true"}}, &notes)
+	if s.offline {
+		return s.cachedNotesByTags(tags)
+	}
+	return notes, err
+}
+
+// GetByCommand returns the notes with the command
+func (s *Store) GetByCommand(ctx context.Context, command string) (interface{}, error) {
+	notes := []Note{}
+	err := s.read(ctx, "/api/notes", url.Values{"command": {command}, "reveal": {"true"}}, &notes)
+	if s.offline {
+		return s.cachedNotesByCommand(command)
+	}
+	return withCommand(notes, command), err
+}
+
+// GetAll returns all notes, they're kept in the cache
+func (s *Store) GetAll(ctx context.Context) (interface{}, error) {
+	notes := []Note{}
+	err := s.read(ctx, "/api/notes", url.Values{"reveal": {"true"}}, &notes)
+	if s.offline {
+		return s.cachedNotes()
+	}
+	if err == nil {
+		s.saveCache(notes)
+	}
+	return notes, err
+}
+
+// GetTags returns all available tags
+func (s *Store) GetTags(ctx context.Context) ([]string, error) {
+	tags := []string{}
+	err := s.read(ctx, "/api/tags", nil, &tags)
+	if s.offline {
+		return s.cachedTags()
+	}
+	return tags, err
+}
+
+// Delete deletes a note by id, or by the start of its id when only one note matches
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return s.do(ctx, http.MethodDelete, "/api/notes/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// DeleteByTags deletes the notes with any of the tags
+func (s *Store) DeleteByTags(ctx context.Context, tags []string) error {
+	return s.do(ctx, http.MethodDelete, "/api/notes", url.Values{"tag": tags}, nil, nil)
+}
+
+// Search returns the notes matching the query, best matches first.
+// The server parses the input of the query again.
+func (s *Store) Search(ctx context.Context, q *search.Query) (interface{}, error) {
+	params := url.Values{
+		"q":             {q.Input},
+		"field":         q.Fields,
+		"exact":         {strconv.FormatBool(!q.Fuzzy)},
+		"caseSensitive": {strconv.FormatBool(q.CaseSensitive)},
+		"reveal":        {"true"},
+	}
+
+	notes := []Note{}
+	err := s.read(ctx, "/api/search", params, &notes)
+	if s.offline {
+		return s.cachedSearch(q)
+	}
+	return notes, err
+}
+
+// IncrementUses counts a use of a note
+func (s *Store) IncrementUses(ctx context.Context, id string) error {
+	return s.do(ctx, http.MethodPost, "/api/notes/"+url.PathEscape(id)+"/uses", nil, nil, nil)
+}
+
+// read sends a GET request, the storage goes offline when the server can't be reached and the notes are cached
+func (s *Store) read(ctx context.Context, path string, params url.Values, result interface{}) error {
+	if s.offline {
+		return nil
+	}
+
+	err := s.do(ctx, http.MethodGet, path, params, nil, result)
+	if errors.Is(err, errdefs.ErrStorageUnavailable) && s.cacheTime() != "" {
+		logger.Warn("%s, using the notes cached on %s", err, s.cacheTime())
+		s.offline = true
+		return nil
+	}
+	return err
+}
+
+// do sends a request with the body encoded as JSON and decodes the response into result.
+// GET, PUT and DELETE requests are retried when the server can't be reached or is unavailable.
+// A retried DELETE finding nothing succeeds, the response to the first attempt may have been lost.
+func (s *Store) do(ctx context.Context, method string, path string, params url.Values, body interface{}, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	target := s.config.URL + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+
+	retries := 0
+	if method != http.MethodPost {
+		retries = s.config.Retries
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = s.send(ctx, method, target, data, result)
+		if attempt > 0 && method == http.MethodDelete && errors.Is(err, errdefs.ErrNotFound) {
+			return nil
+		}
+		if attempt >= retries || !errors.Is(err, errdefs.ErrStorageUnavailable) {
+			return err
+		}
+
+		select {
+		case <-time.After(retryDelay << attempt):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Store) send(ctx context.Context, method string, target string, data []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return errdefs.Mark(fmt.Errorf("server %s can't be reached: %w", s.config.URL, err), errdefs.ErrStorageUnavailable)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("invalid response from server %s: %w", s.config.URL, err)
+	}
+	return nil
+}
+
+// responseError returns the error of an error response, marked with its kind
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	e := apiError{}
+	if json.Unmarshal(body, &e) != nil || e.Error == "" {
+		e.Error = strings.TrimSpace(string(body))
+		if e.Error == "" {
+			e.Error = resp.Status
+		}
+	}
+
+	err := errors.New(e.Error)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("server refused the token: %s", e.Error)
+	case errorCodes[e.Code] != nil:
+		return errdefs.Mark(err, errorCodes[e.Code])
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+		return errdefs.Mark(fmt.Errorf("server unavailable: %w", err), errdefs.ErrStorageUnavailable)
+	}
+	return fmt.Errorf("server error: %w", err)
+}
@@ -0,0 +1,307 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/search"
+)
+
+// recorder counts the requests reaching a test server and when they were received
+type recorder struct {
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func (r *recorder) record() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.times = append(r.times, time.Now())
+	return len(r.times)
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.times)
+}
+
+func newStore(t *testing.T, url string, retries int) *Store {
+	t.Helper()
+	store, err := Initialize(&Config{URL: url, Retries: retries, Cache: filepath.Join(t.TempDir(), "cache.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: code + " error", Code: code})
+}
+
+func TestRetries(t *testing.T) {
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = 20 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// failures is the number of requests answered with 503 before the request succeeds
+		failures     int
+		status       int
+		call         func(ctx context.Context, s *Store) error
+		wantRequests int
+		wantErr      error
+	}{
+		{
+			name:         "GET retried until it succeeds",
+			failures:     2,
+			call:         func(ctx context.Context, s *Store) error { _, err := s.GetTags(ctx); return err },
+			wantRequests: 3,
+		},
+		{
+			name:         "GET retried until retries run out",
+			failures:     5,
+			call:         func(ctx context.Context, s *Store) error { _, err := s.GetTags(ctx); return err },
+			wantRequests: 3,
+			wantErr:      errdefs.ErrStorageUnavailable,
+		},
+		{
+			name:         "PUT retried",
+			failures:     1,
+			call:         func(ctx context.Context, s *Store) error { return s.Update(ctx, Note{ID: "123456", Command: "ls"}) },
+			wantRequests: 2,
+		},
+		{
+			name:         "POST not retried, it may have added the note",
+			failures:     1,
+			call:         func(ctx context.Context, s *Store) error { _, err := s.Create(ctx, Note{Command: "ls"}); return err },
+			wantRequests: 1,
+			wantErr:      errdefs.ErrStorageUnavailable,
+		},
+		{
+			name:         "other errors not retried",
+			status:       http.StatusNotFound,
+			call:         func(ctx context.Context, s *Store) error { return s.Update(ctx, Note{ID: "123456", Command: "ls"}) },
+			wantRequests: 1,
+			wantErr:      errdefs.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := &recorder{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := requests.record()
+				switch {
+				case n <= tt.failures:
+					writeError(w, http.StatusServiceUnavailable, "unavailable")
+				case tt.status != 0:
+					writeError(w, tt.status, "not_found")
+				case r.Method == http.MethodPost:
+					w.WriteHeader(http.StatusCreated)
+					json.NewEncoder(w).Encode(Note{ID: "123456"})
+				case r.Method == http.MethodPut:
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					json.NewEncoder(w).Encode([]string{"k8s"})
+				}
+			}))
+			defer srv.Close()
+
+			err := tt.call(context.Background(), newStore(t, srv.URL, 2))
+			if (tt.wantErr == nil && err != nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if requests.count() != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", requests.count(), tt.wantRequests)
+			}
+
+			// the delay doubles on each retry
+			for i := 1; i < len(requests.times); i++ {
+				if gap, want := requests.times[i].Sub(requests.times[i-1]), retryDelay<<(i-1); gap < want {
+					t.Errorf("retry %d after %s, want at least %s", i, gap, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteRetriedAfterLostResponse(t *testing.T) {
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = time.Millisecond
+
+	var mu sync.Mutex
+	notes := map[string]bool{"123456": true}
+	requests := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.record()
+		mu.Lock()
+		defer mu.Unlock()
+
+		id := strings.TrimPrefix(r.URL.Path, "/api/notes/")
+		if !notes[id] {
+			writeError(w, http.StatusNotFound, "not_found")
+			return
+		}
+		delete(notes, id)
+
+		// the note is deleted but the connection drops before the response is sent
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	store := newStore(t, srv.URL, 2)
+	if err := store.Delete(context.Background(), "123456"); err != nil {
+		t.Errorf("Delete() = %v, want the note deleted", err)
+	}
+	if requests.count() != 2 {
+		t.Errorf("got %d requests, want the DELETE retried once", requests.count())
+	}
+
+	// a note that never existed is still not found
+	if err := store.Delete(context.Background(), "654321"); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Delete() of a missing note = %v, want ErrNotFound", err)
+	}
+}
+
+func TestResponseErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		wantErr error
+		wantMsg string
+	}{
+		{http.StatusBadRequest, `{"error": "invalid note", "code": "invalid"}`, errdefs.ErrUsage, "invalid note"},
+		{http.StatusNotFound, `{"error": "note 12 not found", "code": "not_found"}`, errdefs.ErrNotFound, "note 12 not found"},
+		{http.StatusConflict, `{"error": "ambiguous ID 1", "code": "ambiguous_id"}`, errdefs.ErrAmbiguousID, "ambiguous ID 1"},
+		{http.StatusConflict, `{"error": "merge conflict", "code": "conflict"}`, errdefs.ErrConflict, "merge conflict"},
+		{http.StatusConflict, `{"error": "already in note 1", "code": "duplicate"}`, errdefs.ErrDuplicate, "already in note 1"},
+		{http.StatusServiceUnavailable, `{"error": "mongo is down", "code": "unavailable"}`, errdefs.ErrStorageUnavailable, "mongo is down"},
+		{http.StatusBadGateway, "bad gateway", errdefs.ErrStorageUnavailable, "server unavailable: bad gateway"},
+		{http.StatusGatewayTimeout, "", errdefs.ErrStorageUnavailable, "504 Gateway Timeout"},
+		{http.StatusUnauthorized, `{"error": "missing or wrong token", "code": "unauthorized"}`, nil, "server refused the token"},
+		{http.StatusInternalServerError, `{"error": "no encryption key", "code": "key"}`, nil, "server error: no encryption key"},
+	}
+	kinds := []error{errdefs.ErrUsage, errdefs.ErrNotFound, errdefs.ErrAmbiguousID, errdefs.ErrConflict, errdefs.ErrDuplicate, errdefs.ErrStorageUnavailable}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status)+" "+tt.body, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			store := newStore(t, srv.URL, 0)
+			store.config.Cache = ""
+			_, err := store.Get(context.Background(), "12")
+			if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("got error %v, want %q", err, tt.wantMsg)
+			}
+			for _, kind := range kinds {
+				if errors.Is(err, kind) != (kind == tt.wantErr) {
+					t.Errorf("errors.Is(%v, %v) = %t", err, kind, !(kind == tt.wantErr))
+				}
+			}
+		})
+	}
+}
+
+func TestOfflineCache(t *testing.T) {
+	notes := []Note{
+		{ID: "123456", Tags: []string{"k8s"}, Command: "kubectl get pods", Description: "List the pods"},
+		{ID: "123789", Tags: []string{"db"}, Command: "psql -p hunter2", Description: "Connect", Secret: true},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(notes)
+	}))
+
+	ctx := context.Background()
+	store := newStore(t, srv.URL, 0)
+	if _, err := store.GetAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cache is only readable by its owner and holds no secret command
+	info, err := os.Stat(store.config.Cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %o, want 600", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(store.config.Cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") || !strings.Contains(string(data), secretMask) {
+		t.Errorf("cache holds the secret command: %s", data)
+	}
+
+	// the server may not filter by command
+	byCommand, err := store.GetByCommand(ctx, "kubectl get pods")
+	if err != nil || len(byCommand.([]Note)) != 1 {
+		t.Errorf("GetByCommand() = %v, %v", byCommand, err)
+	}
+
+	srv.Close()
+
+	result, err := store.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll() offline = %v", err)
+	}
+	cached := result.([]Note)
+	if len(cached) != 2 || cached[0].Command != "kubectl get pods" || cached[1].Command != secretMask {
+		t.Errorf("GetAll() offline = %v", cached)
+	}
+
+	note, err := store.Get(ctx, "123456")
+	if err != nil || note.(Note).Description != "List the pods" {
+		t.Errorf("Get() offline = %v, %v", note, err)
+	}
+	if _, err := store.Get(ctx, "123"); !errors.Is(err, errdefs.ErrAmbiguousID) {
+		t.Errorf("Get() of an ambiguous ID offline = %v, want ErrAmbiguousID", err)
+	}
+
+	byTags, err := store.GetByTags(ctx, []string{"db"})
+	if err != nil || len(byTags.([]Note)) != 1 {
+		t.Errorf("GetByTags() offline = %v, %v", byTags, err)
+	}
+
+	byCommand, err = store.GetByCommand(ctx, "kubectl get pods")
+	if err != nil || len(byCommand.([]Note)) != 1 || byCommand.([]Note)[0].ID != "123456" {
+		t.Errorf("GetByCommand() offline = %v, %v", byCommand, err)
+	}
+
+	q, err := search.Parse("kubctl", search.Locations, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Fuzzy = true
+	found, err := store.Search(ctx, q)
+	if err != nil || len(found.([]Note)) != 1 || found.([]Note)[0].ID != "123456" {
+		t.Errorf("Search() offline = %v, %v", found, err)
+	}
+
+	// changes aren't cached, they fail
+	if err := store.Update(ctx, notes[0]); !errors.Is(err, errdefs.ErrStorageUnavailable) {
+		t.Errorf("Update() offline = %v, want ErrStorageUnavailable", err)
+	}
+}
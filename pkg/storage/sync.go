@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	yaml "github.com/carloscastrojumo/remindme/pkg/storage/yaml"
+)
+
+// ErrSyncNotSupported is returned by Sync when the storage can't be synced
+var ErrSyncNotSupported = errors.New("sync is only supported by the YAML storage")
+
+// Syncer is implemented by storages that sync their data with a remote copy
+type Syncer interface {
+	Sync(ctx context.Context) (*yaml.SyncResult, error)
+}
+
+// Sync exchanges the changes of the notes with their remote copy
+func (s *NoteService) Sync(ctx context.Context) (*yaml.SyncResult, error) {
+	syncer, ok := s.store.(Syncer)
+	if !ok {
+		return nil, ErrSyncNotSupported
+	}
+	return syncer.Sync(ctx)
+}
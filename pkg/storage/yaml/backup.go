@@ -0,0 +1,229 @@
+package yaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+)
+
+// DefaultBackups is the number of backups kept when the config doesn't set it
+const DefaultBackups = 20
+
+// backupLayout names the backups after the time they were taken, in UTC
+const backupLayout = "20060102-150405.000"
+
+// backupSeparator separates the timestamp of a backup from its sequence number,
+// given to the backups taken in the same millisecond, e.g. 20240102-150405.000_2
+const backupSeparator = "_"
+
+// Backup is a copy of the data file taken before it was changed
+type Backup struct {
+	// Name is the backup timestamp, used to restore it
+	Name string
+	Time time.Time
+	Path string
+	Size int64
+	// seq orders the backups taken in the same millisecond
+	seq int
+}
+
+// backupDir returns the directory where the backups of a data file are kept
+func backupDir(name string) string {
+	return name + ".backups"
+}
+
+// Backups returns the backups of the data file, oldest first
+func (y *Yaml) Backups() ([]Backup, error) {
+	entries, err := os.ReadDir(backupDir(y.File.Name()))
+	if os.IsNotExist(err) {
+		return []Backup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading backups: %w", err)
+	}
+
+	backups := []Backup{}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		timestamp, suffix, found := strings.Cut(name, backupSeparator)
+		t, err := time.Parse(backupLayout, timestamp)
+		if entry.IsDir() || err != nil {
+			continue
+		}
+		seq := 1
+		if found {
+			if seq, err = strconv.Atoi(suffix); err != nil {
+				continue
+			}
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{
+			Name: name,
+			Time: t,
+			Path: filepath.Join(backupDir(y.File.Name()), entry.Name()),
+			Size: info.Size(),
+			seq:  seq,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.Before(backups[j].Time)
+		}
+		return backups[i].seq < backups[j].seq
+	})
+	return backups, nil
+}
+
+// CreateBackup takes a backup of the data file as it is now
+func (y *Yaml) CreateBackup() (Backup, error) {
+	var backup Backup
+	err := y.withLock(func() error {
+		data, err := os.ReadFile(y.File.Name())
+		if err != nil {
+			return fmt.Errorf("error while reading notes file: %w", err)
+		}
+		backup, err = y.backup(data)
+		return err
+	})
+	return backup, err
+}
+
+// FindBackup returns the backup with the given name, or the only one starting with it
+func (y *Yaml) FindBackup(name string) (Backup, error) {
+	backups, err := y.Backups()
+	if err != nil {
+		return Backup{}, err
+	}
+
+	found := []Backup{}
+	for _, backup := range backups {
+		if backup.Name == name {
+			return backup, nil
+		}
+		if strings.HasPrefix(backup.Name, name) {
+			found = append(found, backup)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return Backup{}, errdefs.Mark(fmt.Errorf("backup %q not found", name), errdefs.ErrNotFound)
+	case 1:
+		return found[0], nil
+	}
+	return Backup{}, errdefs.Mark(fmt.Errorf("backup %q is ambiguous, it matches %d backups", name, len(found)), errdefs.ErrAmbiguousID)
+}
+
+// BackupNotes returns the notes saved in a backup
+func (y *Yaml) BackupNotes(name string) (interface{}, error) {
+	backup, err := y.FindBackup(name)
+	if err != nil {
+		return nil, err
+	}
+	return y.loadBackup(backup)
+}
+
+// RestoreBackup replaces the notes with the ones saved in a backup,
+// the notes being replaced are backed up first so the restore can be undone
+func (y *Yaml) RestoreBackup(name string) error {
+	backup, err := y.FindBackup(name)
+	if err != nil {
+		return err
+	}
+
+	notes, err := y.loadBackup(backup)
+	if err != nil {
+		return err
+	}
+
+	return y.update(context.Background(), func([]Note) ([]Note, string, error) {
+		return notes, "Restore backup " + backup.Name, nil
+	})
+}
+
+func (y *Yaml) loadBackup(backup Backup) ([]Note, error) {
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading backup %s: %w", backup.Name, err)
+	}
+
+	notes, err := y.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s is not a valid notes file: %w", backup.Name, err)
+	}
+	return y.openAll(notes)
+}
+
+// backup saves the data and removes the backups exceeding the configured count and age,
+// it must be called while holding the lock of the data file
+func (y *Yaml) backup(data []byte) (Backup, error) {
+	dir := backupDir(y.File.Name())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Backup{}, fmt.Errorf("error while backing up notes file: %w", err)
+	}
+
+	now := time.Now().UTC()
+	timestamp := now.Format(backupLayout)
+	for seq := 1; ; seq++ {
+		name := timestamp
+		if seq > 1 {
+			name += backupSeparator + strconv.Itoa(seq)
+		}
+		path := filepath.Join(dir, name+".yaml")
+		err := writeNewFile(path, data)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return Backup{}, fmt.Errorf("error while backing up notes file: %w", err)
+		}
+
+		y.pruneBackups()
+		return Backup{Name: name, Time: now, Path: path, Size: int64(len(data)), seq: seq}, nil
+	}
+}
+
+// writeNewFile writes a file that doesn't exist yet, it fails with fs.ErrExist when it does
+func writeNewFile(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err := writeAndSync(f, data, 0644); err != nil {
+		f.Close()
+		os.Remove(name)
+		return err
+	}
+	return nil
+}
+
+// pruneBackups removes the oldest backups over the configured count and the ones older than the configured age
+func (y *Yaml) pruneBackups() {
+	backups, err := y.Backups()
+	if err != nil {
+		return
+	}
+
+	for i, backup := range backups {
+		tooMany := y.config.Backups > 0 && len(backups)-i > y.config.Backups
+		tooOld := y.config.BackupMaxAge > 0 && time.Since(backup.Time) > y.config.BackupMaxAge
+		// the latest backup is always kept
+		if (tooMany || tooOld) && i < len(backups)-1 {
+			os.Remove(backup.Path)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupsTakenTogetherAreKept(t *testing.T) {
+	y, err := Initialize(&Config{Name: filepath.Join(t.TempDir(), "notes.yaml"), Backups: DefaultBackups})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// backups taken in the same millisecond get a sequence number instead of replacing each other
+	contents := []string{"- id: \"1\"\n", "- id: \"2\"\n", "- id: \"3\"\n"}
+	for _, content := range contents {
+		if _, err := y.backup([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := y.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != len(contents) {
+		t.Fatalf("got %d backups, want %d", len(backups), len(contents))
+	}
+	for i, backup := range backups {
+		data, err := os.ReadFile(backup.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != contents[i] {
+			t.Errorf("backup %s holds %q, want %q", backup.Name, data, contents[i])
+		}
+	}
+}
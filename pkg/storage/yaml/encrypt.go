@@ -0,0 +1,163 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// sealedCommand is the ciphertext of a secret command as it was read, it's written back unchanged
+// while the command doesn't change so saving doesn't change the file for nothing (e.g. for git)
+type sealedCommand struct {
+	Plain  string
+	Sealed string
+}
+
+// openFile returns the content of the data file, decrypted when it's encrypted
+func openFile(data []byte, cipher *crypt.Cipher) ([]byte, error) {
+	if !crypt.IsEncryptedFile(data) {
+		return data, nil
+	}
+	plain, err := cipher.DecryptFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("error while decrypting notes file: %w", err)
+	}
+	return plain, nil
+}
+
+// sealFile returns the content to write to the data file, encrypted when the storage is configured so
+func (y *Yaml) sealFile(data []byte) ([]byte, error) {
+	if !y.config.Encrypt {
+		return data, nil
+	}
+	sealed, err := y.config.Cipher.EncryptFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("error while encrypting notes file: %w", err)
+	}
+	return sealed, nil
+}
+
+// decode parses the content of the data file. Secret commands are left encrypted until
+// their notes are read, see open.
+func (y *Yaml) decode(data []byte) ([]Note, error) {
+	return decode(data, y.config.Cipher)
+}
+
+// open returns the note with its command decrypted when it's secret. Decrypted commands are
+// cached, without a key they're left encrypted.
+func (y *Yaml) open(note Note) (Note, error) {
+	if !note.Secret || !crypt.IsEncryptedField(note.Command) || y.config.Cipher == nil {
+		return note, nil
+	}
+	if y.sealed == nil {
+		y.sealed = make(map[string]sealedCommand)
+	}
+	if sealed, ok := y.sealed[note.ID]; ok && sealed.Sealed == note.Command {
+		note.Command = sealed.Plain
+		return note, nil
+	}
+
+	command, err := y.config.Cipher.DecryptField(note.Command)
+	if err != nil {
+		return note, fmt.Errorf("error while decrypting note %s: %w", note.ID, err)
+	}
+	y.sealed[note.ID] = sealedCommand{Plain: command, Sealed: note.Command}
+	note.Command = command
+	return note, nil
+}
+
+// openAll returns the notes with their secret commands decrypted
+func (y *Yaml) openAll(notes []Note) ([]Note, error) {
+	opened := make([]Note, len(notes))
+	for i, note := range notes {
+		var err error
+		if opened[i], err = y.open(note); err != nil {
+			return nil, err
+		}
+	}
+	return opened, nil
+}
+
+// openSecrets decrypts the secret commands of the loaded notes so searches find them, the index is
+// rebuilt when one was still encrypted
+func (y *Yaml) openSecrets() error {
+	changed := false
+	for i, note := range y.Notes {
+		opened, err := y.open(note)
+		if err != nil {
+			return err
+		}
+		if opened.Command != note.Command {
+			y.Notes[i], changed = opened, true
+		}
+	}
+	if changed {
+		y.index = buildIndex(y.Notes, y.getIndex().Hash)
+	}
+	return nil
+}
+
+// decode parses the content of the data file, secret commands are left encrypted
+func decode(data []byte, cipher *crypt.Cipher) ([]Note, error) {
+	plain, err := openFile(data, cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []Note
+	if err := yaml.Unmarshal(plain, &notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// encode returns the content of the data file for the notes, with the secret commands encrypted.
+// Without a key secret commands that aren't encrypted yet, e.g. written by hand, are left as they are
+// so the other notes can still be changed.
+func (y *Yaml) encode(notes []Note) ([]byte, error) {
+	if y.sealed == nil {
+		y.sealed = make(map[string]sealedCommand)
+	}
+	plain := []string{}
+	stored := make([]Note, len(notes))
+	for i, note := range notes {
+		stored[i] = note
+		if !note.Secret || crypt.IsEncryptedField(note.Command) && (y.config.Cipher == nil || y.config.Cipher.IsCurrentField(note.Command)) {
+			continue
+		}
+		if y.config.Cipher == nil {
+			plain = append(plain, note.ID)
+			continue
+		}
+
+		// commands encrypted with an older format or salt are encrypted again
+		note, err := y.open(note)
+		if err != nil {
+			return nil, err
+		}
+		if sealed, ok := y.sealed[note.ID]; ok && sealed.Plain == note.Command && y.config.Cipher.IsCurrentField(sealed.Sealed) {
+			stored[i].Command = sealed.Sealed
+			continue
+		}
+
+		command, err := y.config.Cipher.EncryptField(note.Command)
+		if err != nil {
+			return nil, fmt.Errorf("error while encrypting note %s: %w", note.ID, err)
+		}
+		y.sealed[note.ID] = sealedCommand{Plain: note.Command, Sealed: command}
+		stored[i].Command = command
+	}
+
+	if len(plain) > 0 {
+		logger.Warn("The commands of the secret notes %s aren't encrypted: %s", strings.Join(plain, ", "), crypt.ErrNoKey)
+	}
+
+	data, err := yaml.Marshal(stored)
+	if err != nil {
+		return nil, fmt.Errorf("error while marshalling notes: %w", err)
+	}
+	return y.sealFile(data)
+}
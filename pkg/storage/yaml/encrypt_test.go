@@ -0,0 +1,53 @@
+package yaml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+)
+
+func TestPlainSecretWithoutKey(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "notes.yaml")
+	// e.g. a secret note written by hand
+	data := "- id: \"1\"\n  command: psql -p hunter2\n  secret: true\n"
+	if err := os.WriteFile(name, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	y, err := Initialize(&Config{Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the other notes can still be changed, the secret command is kept as it is
+	if _, err := y.Create(ctx, Note{Command: "kubectl get pods"}); err != nil {
+		t.Fatalf("Create() without a key = %v", err)
+	}
+	written, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(written), "psql -p hunter2") || !strings.Contains(string(written), "kubectl get pods") {
+		t.Errorf("data file = %s", written)
+	}
+
+	// with a key it's encrypted on the next write
+	y, err = Initialize(&Config{Name: name, Cipher: crypt.New("test key")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := y.Create(ctx, Note{Command: "git status"}); err != nil {
+		t.Fatal(err)
+	}
+	if written, _ := os.ReadFile(name); strings.Contains(string(written), "hunter2") {
+		t.Errorf("data file with a key = %s, want the secret command encrypted", written)
+	}
+	found, err := y.Get(ctx, "1")
+	if err != nil || found.(Note).Command != "psql -p hunter2" {
+		t.Errorf("Get() = %v, %v", found, err)
+	}
+}
@@ -0,0 +1,302 @@
+package yaml
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/search"
+)
+
+// indexVersion changes whenever the index format does, to rebuild old sidecar files
+const indexVersion = 3
+
+// index is an inverted index of the notes, kept in a sidecar file next to the data file with the notes
+// so that the data file isn't parsed again. It's only valid for the data file content it was built from,
+// identified by its hash.
+type index struct {
+	Version int
+	Hash    string
+	// Notes are the notes of the data file, they're only set while the sidecar file is read or written
+	Notes []Note
+	// Tags maps each tag to the positions of the notes that have it
+	Tags map[string][]int
+	// Commands maps each command to the positions of the notes with it, e.g. to find duplicates
+	Commands map[string][]int
+	// Trigrams maps each searchable field to the lower case trigrams of its values,
+	// and each trigram to the positions of the notes that contain it in the field
+	Trigrams map[string]map[string][]int
+	// documents are the searchable content of the notes by position, they aren't saved
+	documents []search.Document
+}
+
+// indexFile returns the sidecar file of a data file
+func indexFile(name string) string {
+	return name + ".idx"
+}
+
+// hashData identifies the content of a data file
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readIndex reads the sidecar index of the data file and the notes saved with it,
+// it returns nil when it's missing or wasn't built from the data with the hash
+func readIndex(name string, hash string) (*index, []Note) {
+	f, err := os.Open(indexFile(name))
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	var idx index
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&idx); err != nil || idx.Version != indexVersion || idx.Hash != hash {
+		return nil, nil
+	}
+	notes := idx.Notes
+	idx.Notes = nil
+	idx.documents = documents(notes)
+	return &idx, notes
+}
+
+// save writes the index and the notes to the sidecar file, the index is only a cache so errors are ignored
+func (idx *index) save(name string, notes []Note) {
+	f, err := os.CreateTemp(filepath.Dir(name), ".rmm-index-*")
+	if err != nil {
+		return
+	}
+
+	idx.Notes = notes
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(idx)
+	idx.Notes = nil
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return
+	}
+
+	f.Close()
+	if err := os.Rename(f.Name(), indexFile(name)); err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// persistIndex reports whether the index of the notes can be saved in the sidecar file,
+// it holds the content of the notes so it's not kept on disk when they're encrypted
+func persistIndex(config *Config, notes []Note) bool {
+	if config.Encrypt {
+		return false
+	}
+	for _, note := range notes {
+		if note.Secret {
+			return false
+		}
+	}
+	return true
+}
+
+func buildIndex(notes []Note, hash string) *index {
+	idx := &index{
+		Version:   indexVersion,
+		Hash:      hash,
+		Tags:      make(map[string][]int),
+		Commands:  make(map[string][]int),
+		Trigrams:  make(map[string]map[string][]int),
+		documents: documents(notes),
+	}
+	for _, field := range search.Locations {
+		idx.Trigrams[field] = make(map[string][]int)
+	}
+
+	for pos, note := range notes {
+		idx.Commands[note.Command] = append(idx.Commands[note.Command], pos)
+		idx.add("command", pos, note.Command)
+		idx.add("description", pos, note.Description)
+		for _, tag := range note.Tags {
+			idx.add("tags", pos, tag)
+			idx.Tags[tag] = appendPosition(idx.Tags[tag], pos)
+		}
+	}
+
+	return idx
+}
+
+// add indexes the trigrams of a value of a field of the note at the position
+func (idx *index) add(field string, pos int, value string) {
+	postings := idx.Trigrams[field]
+	for _, trigram := range trigrams(strings.ToLower(value)) {
+		postings[trigram] = appendPosition(postings[trigram], pos)
+	}
+}
+
+// appendPosition adds the position to the postings once, positions are added in increasing order
+func appendPosition(postings []int, pos int) []int {
+	if len(postings) > 0 && postings[len(postings)-1] == pos {
+		return postings
+	}
+	return append(postings, pos)
+}
+
+// byTags returns the positions of the notes with any of the tags, in storage order
+func (idx *index) byTags(tags []string) []int {
+	positions := [][]int{}
+	for _, tag := range tags {
+		positions = append(positions, idx.Tags[tag])
+	}
+	return union(positions)
+}
+
+// candidates returns the positions of the notes that may match the query exactly, in storage order.
+// A note can only contain a word in a field if the field contains all of its trigrams.
+func (idx *index) candidates(q *search.Query) []int {
+	clauses := [][]int{}
+	for _, clause := range q.Clauses {
+		var clauseCandidates []int
+		indexed := false
+
+		for _, term := range clause.Terms {
+			if !idx.indexes(term) {
+				continue
+			}
+
+			termCandidates := idx.lookup(term.Fields, term.Value)
+			if !indexed {
+				clauseCandidates, indexed = termCandidates, true
+			} else {
+				clauseCandidates = intersect(clauseCandidates, termCandidates)
+			}
+		}
+
+		if !indexed {
+			return idx.all()
+		}
+		clauses = append(clauses, clauseCandidates)
+	}
+	return union(clauses)
+}
+
+// indexes reports whether the notes matching the term can be looked up,
+// regular expressions, negations, short words and fields without trigrams can't
+func (idx *index) indexes(term search.Term) bool {
+	if term.Regex != nil || term.Negate || len([]rune(term.Value)) < 3 {
+		return false
+	}
+	for _, field := range term.Fields {
+		if _, ok := idx.Trigrams[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the positions of the notes with one of the fields containing every trigram of the word
+func (idx *index) lookup(fields []string, word string) []int {
+	wordTrigrams := trigrams(strings.ToLower(word))
+	found := [][]int{}
+	for _, field := range fields {
+		lists := make([][]int, len(wordTrigrams))
+		for i, trigram := range wordTrigrams {
+			lists[i] = idx.Trigrams[field][trigram]
+		}
+		// the shortest postings first, the intersection only gets shorter
+		sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+
+		result := lists[0]
+		for _, postings := range lists[1:] {
+			if len(result) == 0 {
+				break
+			}
+			result = intersect(result, postings)
+		}
+		found = append(found, result)
+	}
+	return union(found)
+}
+
+func (idx *index) all() []int {
+	positions := make([]int, len(idx.documents))
+	for i := range positions {
+		positions[i] = i
+	}
+	return positions
+}
+
+func documents(notes []Note) []search.Document {
+	docs := make([]search.Document, len(notes))
+	for i, note := range notes {
+		docs[i] = search.Document{Command: note.Command, Description: note.Description, Tags: note.Tags, Uses: note.Uses}
+	}
+	return docs
+}
+
+func trigrams(text string) []string {
+	runes := []rune(text)
+	result := []string{}
+	for i := 0; i+3 <= len(runes); i++ {
+		result = append(result, string(runes[i:i+3]))
+	}
+	return result
+}
+
+// intersect returns the positions in both sorted lists
+func intersect(a []int, b []int) []int {
+	result := make([]int, 0, min(len(a), len(b)))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// union returns the positions in any of the sorted lists, sorted
+func union(lists [][]int) []int {
+	result := []int{}
+	for i, list := range lists {
+		if i == 0 {
+			result = list
+			continue
+		}
+		result = mergePositions(result, list)
+	}
+	return result
+}
+
+// mergePositions returns the positions in either sorted list, sorted
+func mergePositions(a []int, b []int) []int {
+	result := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
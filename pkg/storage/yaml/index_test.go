@@ -0,0 +1,401 @@
+package yaml
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/carloscastrojumo/remindme/pkg/search"
+)
+
+var indexNotes = []Note{
+	{ID: "1", Tags: []string{"k8s"}, Command: "kubectl get pods --all-namespaces", Description: "List the pods"},
+	{ID: "2", Tags: []string{"k8s", "helm"}, Command: "helm list --all", Description: "List the releases of the cluster"},
+	{ID: "3", Tags: []string{"docker"}, Command: "docker ps -a", Description: "List all containers, kubectl isn't needed"},
+	{ID: "4", Tags: []string{"git"}, Command: "git log --oneline --graph", Description: "Show the history", Uses: 3},
+	{ID: "5", Tags: []string{"git", "pods"}, Command: "git push --force-with-lease", Description: "Push over the remote branch"},
+	{ID: "6", Tags: []string{"Réseau"}, Command: "ip addr show", Description: "Afficher les adresses réseau"},
+	{ID: "7", Tags: []string{"db"}, Command: "psql -h localhost", Description: "Connect to the GIT database"},
+}
+
+// ranking lists the IDs and scores of the found notes
+func ranking(notes []Note) string {
+	list := []string{}
+	for _, note := range notes {
+		list = append(list, fmt.Sprintf("%s:%.2f", note.ID, note.Score))
+	}
+	return strings.Join(list, " ")
+}
+
+func TestIndexedSearchMatchesScan(t *testing.T) {
+	y := newStorage(t, indexNotes)
+
+	tests := []struct {
+		query         string
+		caseSensitive bool
+		fuzzy         bool
+		want          string
+	}{
+		{query: "kubectl", want: "1 3"},
+		{query: "tag:k8s", want: "1 2"},
+		{query: "cmd:kubectl", want: "1"},
+		{query: "desc:list", want: "1 2 3"},
+		{query: "tag:pods", want: "5"},
+		{query: "cmd:pods", want: "1"},
+		{query: "list -tag:helm", want: "1 3"},
+		{query: "tag:helm OR cmd:push", want: "2 5"},
+		{query: "tag:git desc:remote", want: "5"},
+		{query: `"get pods"`, want: "1"},
+		{query: `cmd:"--all"`, want: "1 2"},
+		{query: "/ps?ql/", want: "7"},
+		{query: "ip", want: "6"},
+		{query: "réseau", want: "6"},
+		{query: "tag:réseau", want: "6"},
+		{query: "git", want: "4 5 7"},
+		{query: "GIT", caseSensitive: true, want: "7"},
+		{query: "desc:git", caseSensitive: true, want: ""},
+		{query: "kubctl", fuzzy: true, want: "1 3"},
+		{query: "nothing", fuzzy: true, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := search.Parse(tt.query, search.Locations, tt.caseSensitive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.Fuzzy = tt.fuzzy
+
+			result, err := y.Search(context.Background(), q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			indexed, scanned := ranking(result.([]Note)), ranking(y.searchScan(q))
+			if indexed != scanned {
+				t.Errorf("indexed search = %q, scan = %q", indexed, scanned)
+			}
+
+			found := []string{}
+			for _, note := range result.([]Note) {
+				found = append(found, note.ID)
+			}
+			if got := strings.Join(found, " "); !sameIDs(got, tt.want) {
+				t.Errorf("found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// sameIDs compares lists of IDs regardless of their order, the ranking is compared to the scan
+func sameIDs(a string, b string) bool {
+	sortedA, sortedB := strings.Fields(a), strings.Fields(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
+
+func TestIndexedGetByTagsMatchesScan(t *testing.T) {
+	y := newStorage(t, indexNotes)
+
+	for _, tags := range [][]string{{"k8s"}, {"git", "pods"}, {"helm", "k8s"}, {"Réseau"}, {"réseau"}, {"missing"}, {}} {
+		result, err := y.GetByTags(context.Background(), tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if indexed, scanned := ranking(result.([]Note)), ranking(y.getByTagsScan(tags)); indexed != scanned {
+			t.Errorf("GetByTags(%q) = %q, scan = %q", tags, indexed, scanned)
+		}
+	}
+}
+
+func TestIndexInvalidation(t *testing.T) {
+	ctx := context.Background()
+	y := newStorage(t, indexNotes)
+	name := y.File.Name()
+
+	hashFile := func() string {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hashData(data)
+	}
+	searchIDs := func(y *Yaml, input string) string {
+		q, err := search.Parse(input, search.Locations, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := y.Search(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := []string{}
+		for _, note := range result.([]Note) {
+			found = append(found, note.ID)
+		}
+		return strings.Join(found, " ")
+	}
+
+	previousHash := hashFile()
+	if idx, notes := readIndex(name, previousHash); idx == nil || len(notes) != len(indexNotes) {
+		t.Fatal("no sidecar index for the data file")
+	}
+
+	// another process changes the data file
+	other, err := Initialize(&Config{Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Update(ctx, Note{ID: "1", Tags: []string{"k8s"}, Command: "kubectl get nodes"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Create(ctx, Note{Command: "terraform plan", Tags: []string{"iac"}}); err != nil {
+		t.Fatal(err)
+	}
+	hash := hashFile()
+
+	tests := []struct {
+		name string
+		load func() *Yaml
+	}{
+		{
+			name: "initialized",
+			load: func() *Yaml {
+				y, err := Initialize(&Config{Name: name})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return y
+			},
+		},
+		{
+			name: "refreshed",
+			load: func() *Yaml {
+				if err := y.Refresh(ctx); err != nil {
+					t.Fatal(err)
+				}
+				return y
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the data file changed without its sidecar index, e.g. by git, the index of the previous content is left
+			buildIndex(indexNotes, previousHash).save(name, indexNotes)
+			if idx, _ := readIndex(name, hash); idx != nil {
+				t.Fatal("the sidecar index of the previous content is valid for the changed data file")
+			}
+
+			loaded := tt.load()
+			if got := searchIDs(loaded, "pods"); got != "5" {
+				t.Errorf("search of the removed command = %q, want the tag only", got)
+			}
+			if got := searchIDs(loaded, "cmd:nodes"); got != "1" {
+				t.Errorf("search of the changed command = %q, want 1", got)
+			}
+			if got := searchIDs(loaded, "tag:iac"); len(strings.Fields(got)) != 1 {
+				t.Errorf("search of the added note = %q, want it found", got)
+			}
+			if idx, notes := readIndex(name, hash); idx == nil || len(notes) != len(indexNotes)+1 {
+				t.Errorf("the sidecar index wasn't rebuilt for the changed data file")
+			}
+		})
+	}
+}
+
+func TestIndexAfterFailedUpdate(t *testing.T) {
+	ctx := context.Background()
+	y := newStorage(t, indexNotes)
+
+	// another process swaps two notes, the number of notes stays the same
+	other, err := Initialize(&Config{Name: y.File.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.update(ctx, func(notes []Note) ([]Note, string, error) {
+		notes[0], notes[1] = notes[1], notes[0]
+		return notes, "", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the change fails after the notes are read again
+	if err := y.Delete(ctx, "missing"); err == nil {
+		t.Fatal("Delete() of a missing note succeeded")
+	}
+
+	q, err := search.Parse("cmd:kubectl", search.Locations, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := y.Search(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := result.([]Note); len(found) != 1 || found[0].ID != "1" {
+		t.Errorf("Search() = %v, want note 1", found)
+	}
+	byTags, err := y.GetByTags(ctx, []string{"helm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := byTags.([]Note); len(found) != 1 || found[0].ID != "2" {
+		t.Errorf("GetByTags() = %v, want note 2", found)
+	}
+}
+
+const benchmarkNotes = 10000
+
+var benchmarkWords = []string{"kubectl", "docker", "git", "ssh", "terraform", "helm", "aws", "grep", "curl", "systemctl"}
+
+func TestGetByCommand(t *testing.T) {
+	ctx := context.Background()
+	y := newStorage(t, slices.Clone(indexNotes))
+
+	ids := func(command string) string {
+		t.Helper()
+		found, err := y.GetByCommand(ctx, command)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list := []string{}
+		for _, note := range found.([]Note) {
+			list = append(list, note.ID)
+		}
+		return strings.Join(list, " ")
+	}
+	if got := ids("docker ps -a"); got != "3" {
+		t.Errorf("GetByCommand() = %s, want note 3", got)
+	}
+	if got := ids("docker ps"); got != "" {
+		t.Errorf("GetByCommand() of a part of a command = %s, want nothing", got)
+	}
+
+	// the index follows the changes
+	note := indexNotes[2]
+	note.Command = "docker ps --all"
+	if err := y.Update(ctx, note); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := y.Create(ctx, Note{Command: "git log --oneline --graph"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids("docker ps -a"); got != "" {
+		t.Errorf("GetByCommand() of a changed command = %s, want nothing", got)
+	}
+	if found, _ := y.GetByCommand(ctx, "git log --oneline --graph"); len(found.([]Note)) != 2 {
+		t.Errorf("GetByCommand() = %v, want the note and its duplicate", found)
+	}
+}
+
+// newStorage writes a YAML data file with the notes and initializes the storage from it
+func newStorage(tb testing.TB, notes []Note) *Yaml {
+	tb.Helper()
+
+	name := filepath.Join(tb.TempDir(), "notes.yaml")
+	y, err := Initialize(&Config{Name: name})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	y.Notes = notes
+	if err := y.save(); err != nil {
+		tb.Fatal(err)
+	}
+
+	y, err = Initialize(&Config{Name: name})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return y
+}
+
+// benchmarkStorage initializes the storage with generated notes
+func benchmarkStorage(b *testing.B) *Yaml {
+	b.Helper()
+
+	notes := make([]Note, benchmarkNotes)
+	for i := range notes {
+		word := benchmarkWords[i%len(benchmarkWords)]
+		notes[i] = Note{
+			ID:          fmt.Sprint(i),
+			Tags:        []string{word, fmt.Sprintf("project%d", i%100)},
+			Command:     fmt.Sprintf("%s run --name task%d --replicas %d", word, i, i%7),
+			Description: fmt.Sprintf("Run task number %d with %s", i, word),
+		}
+	}
+	return newStorage(b, notes)
+}
+
+func benchmarkQuery(b *testing.B, input string) *search.Query {
+	b.Helper()
+	q, err := search.Parse(input, search.Locations, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	q.Fuzzy = true
+	return q
+}
+
+func BenchmarkSearchIndexed(b *testing.B) {
+	y := benchmarkStorage(b)
+	q := benchmarkQuery(b, "task4242")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		y.Search(context.Background(), q)
+	}
+}
+
+func BenchmarkSearchScan(b *testing.B) {
+	y := benchmarkStorage(b)
+	q := benchmarkQuery(b, "task4242")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		y.searchScan(q)
+	}
+}
+
+func BenchmarkSearchIndexedQualified(b *testing.B) {
+	y := benchmarkStorage(b)
+	q := benchmarkQuery(b, "tag:helm replicas OR cmd:project42")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		y.Search(context.Background(), q)
+	}
+}
+
+func BenchmarkSearchScanQualified(b *testing.B) {
+	y := benchmarkStorage(b)
+	q := benchmarkQuery(b, "tag:helm replicas OR cmd:project42")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		y.searchScan(q)
+	}
+}
+
+func BenchmarkGetByTagsIndexed(b *testing.B) {
+	y := benchmarkStorage(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		y.GetByTags(context.Background(), []string{"project42"})
+	}
+}
+
+func BenchmarkGetByTagsScan(b *testing.B) {
+	y := benchmarkStorage(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		y.getByTagsScan([]string{"project42"})
+	}
+}
+
+func BenchmarkInitialize(b *testing.B) {
+	y := benchmarkStorage(b)
+	config := &Config{Name: y.File.Name()}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Initialize(config)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package yaml
+
+import "os"
+
+// lockFile does nothing on platforms without file locking
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing on platforms without file locking
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,291 @@
+package yaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/git"
+	"github.com/carloscastrojumo/remindme/pkg/merge"
+)
+
+// DefaultSyncBranch is the branch synced when the config doesn't set one
+const DefaultSyncBranch = "main"
+
+// syncRemoteName is the git remote added when the sync remote is a URL
+const syncRemoteName = "rmm"
+
+// syncAttempts is how many times a sync is retried when the remote changed while syncing
+const syncAttempts = 3
+
+var errPushRejected = errdefs.Mark(errors.New("the remote changed while syncing"), errdefs.ErrConflict)
+
+// SyncResult describes what a sync did
+type SyncResult struct {
+	Remote string
+	Branch string
+	// Pulled is set when changes were received from the remote
+	Pulled bool
+	// Pushed is set when changes were sent to the remote
+	Pushed bool
+	// Conflicts are the notes changed differently on both sides, the local version was kept
+	Conflicts []merge.Conflict
+}
+
+func (y *Yaml) repo() *git.Repo {
+	return &git.Repo{Dir: filepath.Dir(y.File.Name())}
+}
+
+func (y *Yaml) syncBranch() string {
+	if y.config.SyncBranch != "" {
+		return y.config.SyncBranch
+	}
+	return DefaultSyncBranch
+}
+
+// commit commits the data file when syncing is configured, it must be called while holding the lock.
+// Errors are ignored, the changes not committed are committed by the next sync.
+func (y *Yaml) commit(ctx context.Context, message string) {
+	if y.config.SyncRemote == "" || !git.Available() {
+		return
+	}
+
+	repo := y.repo()
+	if repo.IsRepo(ctx) {
+		repo.Commit(ctx, message, filepath.Base(y.File.Name()))
+	}
+}
+
+// Sync commits the pending changes of the data file, merges the changes of the remote note by note
+// and pushes the result. The data file directory becomes a git repository if it isn't in one.
+func (y *Yaml) Sync(ctx context.Context) (*SyncResult, error) {
+	if y.config.SyncRemote == "" {
+		return nil, errors.New("no sync remote configured, run 'rmm config set yaml.sync.remote <url>'")
+	}
+	if !git.Available() {
+		return nil, errors.New("git is required to sync, install it and try again")
+	}
+
+	result := &SyncResult{Branch: y.syncBranch()}
+	err := y.withLock(func() error {
+		repo := y.repo()
+		file := filepath.Base(y.File.Name())
+
+		if err := y.initRepo(ctx, repo, file); err != nil {
+			return err
+		}
+
+		remote, err := y.syncRemote(ctx, repo)
+		if err != nil {
+			return err
+		}
+		result.Remote = remote
+
+		if _, err := repo.Commit(ctx, "Update notes", file); err != nil {
+			return err
+		}
+
+		for attempt := 1; ; attempt++ {
+			err := y.pullAndPush(ctx, repo, file, result)
+			if !errors.Is(err, errPushRejected) || attempt == syncAttempts {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, y.reload()
+}
+
+// initRepo makes the data file directory a git repository, ignoring the files rmm keeps next to the data file
+func (y *Yaml) initRepo(ctx context.Context, repo *git.Repo, file string) error {
+	if repo.IsRepo(ctx) {
+		return nil
+	}
+
+	if err := repo.Init(ctx, y.syncBranch()); err != nil {
+		return err
+	}
+
+	ignored := []string{file + ".idx", file + ".lock", file + ".backups/", "." + file + ".tmp-*", ".rmm-index-*"}
+	if err := os.WriteFile(filepath.Join(repo.Dir, ".gitignore"), []byte(strings.Join(ignored, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("error while writing .gitignore: %w", err)
+	}
+
+	_, err := repo.Commit(ctx, "Start syncing notes", file, ".gitignore")
+	return err
+}
+
+// syncRemote returns the git remote to sync with, the sync remote is either the name of a remote
+// of the repository or a URL, which is added as a remote
+func (y *Yaml) syncRemote(ctx context.Context, repo *git.Repo) (string, error) {
+	remote := y.config.SyncRemote
+	if repo.Succeeds(ctx, "remote", "get-url", remote) {
+		return remote, nil
+	}
+
+	url, err := repo.Run(ctx, "remote", "get-url", syncRemoteName)
+	switch {
+	case err != nil:
+		_, err = repo.Run(ctx, "remote", "add", syncRemoteName, remote)
+	case url != remote:
+		_, err = repo.Run(ctx, "remote", "set-url", syncRemoteName, remote)
+	}
+	return syncRemoteName, err
+}
+
+func (y *Yaml) pullAndPush(ctx context.Context, repo *git.Repo, file string, result *SyncResult) error {
+	heads, err := repo.Run(ctx, "ls-remote", "--heads", result.Remote, result.Branch)
+	if err != nil {
+		return err
+	}
+
+	remoteHead := ""
+	if heads != "" {
+		if _, err := repo.Run(ctx, "fetch", "--quiet", result.Remote, result.Branch); err != nil {
+			return err
+		}
+		if remoteHead, err = repo.Run(ctx, "rev-parse", "FETCH_HEAD"); err != nil {
+			return err
+		}
+		if err := y.mergeRemote(ctx, repo, file, remoteHead, result); err != nil {
+			return err
+		}
+	}
+
+	head, err := repo.Run(ctx, "rev-parse", "HEAD")
+	if err != nil || head == remoteHead {
+		return err
+	}
+
+	if _, err := repo.Run(ctx, "push", "--quiet", result.Remote, "HEAD:refs/heads/"+result.Branch); err != nil {
+		if strings.Contains(err.Error(), "rejected") {
+			return errPushRejected
+		}
+		return err
+	}
+	result.Pushed = true
+	return nil
+}
+
+// mergeRemote merges the remote commit into the local branch, the data file is merged note by note
+func (y *Yaml) mergeRemote(ctx context.Context, repo *git.Repo, file string, remoteHead string, result *SyncResult) error {
+	head, err := repo.Run(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case head == remoteHead || repo.Succeeds(ctx, "merge-base", "--is-ancestor", remoteHead, "HEAD"):
+		// nothing to receive
+		return nil
+	case repo.Succeeds(ctx, "merge-base", "--is-ancestor", "HEAD", remoteHead):
+		err := repo.Merge(ctx, remoteHead, "--quiet", "--ff-only")
+		result.Pulled = err == nil
+		return err
+	}
+
+	// both sides changed, histories without a common commit are merged from an empty data file
+	mergeOptions := []string{"--quiet", "--no-ff", "--no-commit"}
+	var base []byte
+	if mergeBase, err := repo.Run(ctx, "merge-base", "HEAD", remoteHead); err == nil {
+		if base, err = y.showFile(ctx, repo, mergeBase, file); err != nil {
+			return err
+		}
+	} else {
+		mergeOptions = append(mergeOptions, "--allow-unrelated-histories")
+	}
+
+	ours, err := y.showFile(ctx, repo, "HEAD", file)
+	if err != nil {
+		return err
+	}
+	theirs, err := y.showFile(ctx, repo, remoteHead, file)
+	if err != nil {
+		return err
+	}
+
+	data, merged, err := merge.Files(base, ours, theirs)
+	if err != nil {
+		return fmt.Errorf("error while merging notes: %w", err)
+	}
+	if data, err = y.sealFile(data); err != nil {
+		return err
+	}
+
+	// conflicts in the data file are solved by the note merge
+	if err := repo.Merge(ctx, remoteHead, mergeOptions...); err != nil {
+		return err
+	}
+	if err := checkUnmerged(ctx, repo, file); err != nil {
+		repo.Run(ctx, "merge", "--abort")
+		return err
+	}
+
+	if err := writeFile(y.File.Name(), data); err != nil {
+		repo.Run(ctx, "merge", "--abort")
+		return fmt.Errorf("error while writing notes to file: %w", err)
+	}
+	if _, err := repo.Run(ctx, "add", "--", file); err != nil {
+		return err
+	}
+	if err := repo.CommitMerge(ctx, fmt.Sprintf("Merge notes from %s/%s", result.Remote, result.Branch)); err != nil {
+		return err
+	}
+
+	result.Pulled = true
+	result.Conflicts = merged.Conflicts
+	return nil
+}
+
+// checkUnmerged fails when files other than the data file have merge conflicts
+func checkUnmerged(ctx context.Context, repo *git.Repo, file string) error {
+	prefix, err := repo.Run(ctx, "rev-parse", "--show-prefix")
+	if err != nil {
+		return err
+	}
+
+	unmerged, err := repo.Run(ctx, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return err
+	}
+
+	for _, path := range strings.Fields(unmerged) {
+		if path != prefix+file {
+			return errdefs.Mark(fmt.Errorf("merge conflict in %s, it must be solved with git in %s", path, repo.Dir), errdefs.ErrConflict)
+		}
+	}
+	return nil
+}
+
+// showFile returns the data file content of a revision, decrypted when it's encrypted.
+// The commands of secret notes stay encrypted, they're merged as they are.
+func (y *Yaml) showFile(ctx context.Context, repo *git.Repo, revision string, file string) ([]byte, error) {
+	data, err := repo.Show(ctx, revision, file)
+	if errors.Is(err, git.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return openFile(data, y.config.Cipher)
+}
+
+// reload reads the notes from the data file after it was changed outside of update
+func (y *Yaml) reload() error {
+	data, err := os.ReadFile(y.File.Name())
+	if err != nil {
+		return fmt.Errorf("error while reading notes file: %w", err)
+	}
+
+	if err := y.load(data); err != nil {
+		return fmt.Errorf("error while reading notes file: %w", err)
+	}
+	return nil
+}
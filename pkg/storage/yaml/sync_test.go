@@ -0,0 +1,283 @@
+package yaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/git"
+)
+
+// requireGit skips the test without git, and keeps the git configuration of the user out of it
+func requireGit(t *testing.T) {
+	t.Helper()
+	if !git.Available() {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newRemote returns a bare repository to sync with
+func newRemote(t *testing.T) string {
+	t.Helper()
+	remote := filepath.Join(t.TempDir(), "notes.git")
+	runGit(t, ".", "init", "--quiet", "--bare", "--initial-branch", DefaultSyncBranch, remote)
+	return remote
+}
+
+// newClient returns a storage syncing with the remote, in a clone of it when clone is set
+func newClient(t *testing.T, remote string, clone bool) *Yaml {
+	t.Helper()
+	dir := t.TempDir()
+	if clone {
+		runGit(t, ".", "clone", "--quiet", remote, dir)
+	}
+	y, err := Initialize(&Config{Name: filepath.Join(dir, "notes.yaml"), SyncRemote: remote})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return y
+}
+
+func create(t *testing.T, y *Yaml, command string) string {
+	t.Helper()
+	id, err := y.Create(context.Background(), Note{Command: command, Tags: []string{"test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func describe(t *testing.T, y *Yaml, id string, description string) {
+	t.Helper()
+	i, err := find(y.Notes, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := y.Notes[i]
+	note.Description = description
+	if err := y.Update(context.Background(), note); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sync(t *testing.T, y *Yaml) *SyncResult {
+	t.Helper()
+	result, err := y.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+	return result
+}
+
+// contents lists the notes as "command: description", sorted
+func contents(y *Yaml) []string {
+	list := []string{}
+	for _, note := range y.Notes {
+		list = append(list, note.Command+": "+note.Description)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestSyncFastForward(t *testing.T) {
+	requireGit(t)
+	remote := newRemote(t)
+
+	a := newClient(t, remote, false)
+	create(t, a, "kubectl get pods")
+	if result := sync(t, a); !result.Pushed || result.Pulled {
+		t.Errorf("first sync = %+v, want pushed", result)
+	}
+
+	b := newClient(t, remote, true)
+	create(t, a, "kubectl get nodes")
+	sync(t, a)
+
+	result := sync(t, b)
+	if !result.Pulled || result.Pushed {
+		t.Errorf("sync = %+v, want pulled only", result)
+	}
+	if got, want := contents(b), contents(a); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("notes = %v, want %v", got, want)
+	}
+	// a fast-forward doesn't create a merge commit
+	dir := filepath.Dir(b.File.Name())
+	if parents := runGit(t, dir, "rev-list", "--parents", "-n", "1", "HEAD"); len(strings.Fields(parents)) != 2 {
+		t.Errorf("HEAD has parents %q, want a single one", parents)
+	}
+	if head, remoteHead := runGit(t, dir, "rev-parse", "HEAD"), runGit(t, remote, "rev-parse", DefaultSyncBranch); head != remoteHead {
+		t.Errorf("HEAD = %s, want the remote %s", head, remoteHead)
+	}
+}
+
+func TestSyncMergesDivergedNotes(t *testing.T) {
+	requireGit(t)
+
+	tests := []struct {
+		name          string
+		change        func(t *testing.T, a *Yaml, b *Yaml, ids []string)
+		want          []string
+		wantConflicts []string
+	}{
+		{
+			name: "notes added on both sides",
+			change: func(t *testing.T, a *Yaml, b *Yaml, ids []string) {
+				create(t, a, "git status")
+				create(t, b, "git log")
+			},
+			want: []string{"git log: ", "git status: ", "kubectl get nodes: ", "kubectl get pods: "},
+		},
+		{
+			name: "different notes changed on both sides",
+			change: func(t *testing.T, a *Yaml, b *Yaml, ids []string) {
+				describe(t, a, ids[0], "List the pods")
+				describe(t, b, ids[1], "List the nodes")
+			},
+			want: []string{"kubectl get nodes: List the nodes", "kubectl get pods: List the pods"},
+		},
+		{
+			name: "same note changed on both sides",
+			change: func(t *testing.T, a *Yaml, b *Yaml, ids []string) {
+				describe(t, a, ids[0], "List the pods")
+				describe(t, b, ids[0], "Show the pods")
+			},
+			// the local version is kept
+			want:          []string{"kubectl get nodes: ", "kubectl get pods: Show the pods"},
+			wantConflicts: []string{"description"},
+		},
+		{
+			name: "note removed on one side and changed on the other",
+			change: func(t *testing.T, a *Yaml, b *Yaml, ids []string) {
+				if err := a.Delete(context.Background(), ids[1]); err != nil {
+					t.Fatal(err)
+				}
+				describe(t, b, ids[0], "List the pods")
+			},
+			want: []string{"kubectl get pods: List the pods"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newRemote(t)
+			a := newClient(t, remote, false)
+			ids := []string{create(t, a, "kubectl get pods"), create(t, a, "kubectl get nodes")}
+			sync(t, a)
+			b := newClient(t, remote, true)
+
+			tt.change(t, a, b, ids)
+			sync(t, a)
+			result := sync(t, b)
+			if !result.Pulled || !result.Pushed {
+				t.Errorf("sync = %+v, want pulled and pushed", result)
+			}
+			if got := contents(b); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("notes = %q, want %q", got, tt.want)
+			}
+
+			conflicts := []string{}
+			for _, c := range result.Conflicts {
+				conflicts = append(conflicts, c.Fields...)
+			}
+			if fmt.Sprint(conflicts) != fmt.Sprint(append([]string{}, tt.wantConflicts...)) {
+				t.Errorf("conflicts = %v, want %v", conflicts, tt.wantConflicts)
+			}
+
+			// the other side receives the merge as it is
+			sync(t, a)
+			if got := contents(a); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("notes of the other side = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// rejectPushes installs a hook in the remote declining the first pushes, before the first one
+// is declined the pending commits of the other client, when given, are pushed as if it synced meanwhile
+func rejectPushes(t *testing.T, remote string, rejected int, other string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook is a shell script")
+	}
+
+	count := filepath.Join(t.TempDir(), "pushes")
+	script := fmt.Sprintf(`#!/bin/sh
+n=$(cat %[1]q 2>/dev/null || echo 0)
+echo $((n + 1)) > %[1]q
+[ -n "$RMM_TEST_OTHER" ] || [ "$n" -ge %[2]d ] && exit 0
+if [ "$n" -eq 0 ] && [ -n %[3]q ]; then
+	unset $(git rev-parse --local-env-vars) GIT_QUARANTINE_PATH
+	RMM_TEST_OTHER=1 git -C %[3]q push --quiet rmm HEAD:refs/heads/main || exit 2
+fi
+exit 1
+`, count, rejected, other)
+	if err := os.WriteFile(filepath.Join(remote, "hooks", "pre-receive"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestSyncRetriesRejectedPushes(t *testing.T) {
+	requireGit(t)
+	remote := newRemote(t)
+	a := newClient(t, remote, false)
+	id := create(t, a, "kubectl get pods")
+	sync(t, a)
+	b := newClient(t, remote, true)
+
+	// a changes a note and b adds one, the push of b is declined as a synced meanwhile
+	describe(t, a, id, "List the pods")
+	create(t, b, "kubectl get nodes")
+	count := rejectPushes(t, remote, 1, filepath.Dir(a.File.Name()))
+
+	result := sync(t, b)
+	if !result.Pulled || !result.Pushed {
+		t.Errorf("sync = %+v, want pulled and pushed", result)
+	}
+	want := []string{"kubectl get nodes: ", "kubectl get pods: List the pods"}
+	if got := contents(b); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("notes = %q, want %q", got, want)
+	}
+	// the push of a, the declined push of b and its retry
+	if pushes, _ := os.ReadFile(count); strings.TrimSpace(string(pushes)) != "3" {
+		t.Errorf("got %s pushes, want 3", pushes)
+	}
+}
+
+func TestSyncGivesUpOnRejectedPushes(t *testing.T) {
+	requireGit(t)
+	remote := newRemote(t)
+	a := newClient(t, remote, false)
+	create(t, a, "kubectl get pods")
+	sync(t, a)
+
+	create(t, a, "kubectl get nodes")
+	count := rejectPushes(t, remote, 100, "")
+
+	_, err := a.Sync(context.Background())
+	if !errors.Is(err, errdefs.ErrConflict) {
+		t.Errorf("Sync() = %v, want a conflict", err)
+	}
+	if pushes, _ := os.ReadFile(count); strings.TrimSpace(string(pushes)) != fmt.Sprint(syncAttempts) {
+		t.Errorf("got %s pushes, want %d", pushes, syncAttempts)
+	}
+}
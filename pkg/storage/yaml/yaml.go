@@ -1,16 +1,21 @@
 package yaml
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/fatih/color"
-	yaml "gopkg.in/yaml.v3"
+	"github.com/carloscastrojumo/remindme/pkg/crypt"
+	"github.com/carloscastrojumo/remindme/pkg/errdefs"
+	"github.com/carloscastrojumo/remindme/pkg/search"
 )
 
 // Note is a struct that represents a note in YAML storage
@@ -19,113 +24,468 @@ type Note struct {
 	Tags        []string `yaml:"tags"`
 	Command     string   `yaml:"command"`
 	Description string   `yaml:"description"`
+	Uses        int      `yaml:"uses,omitempty"`
+	// Secret notes have their command encrypted in the data file
+	Secret bool `yaml:"secret,omitempty"`
+	// Shell runs the command, e.g. bash or python, the user's shell when it's empty
+	Shell string `yaml:"shell,omitempty"`
+	// Score is the search ranking, it's not stored
+	Score float64 `yaml:"-"`
+}
+
+// title names the note in commit messages, secret commands are left out
+func (n Note) title() string {
+	if n.Secret {
+		return "(secret)"
+	}
+	return n.Command
 }
 
 // Yaml is a struct that represents YAML storage
 type Yaml struct {
 	File  *os.File
 	Notes []Note
+	// hash identifies the content of the data file the notes were read from or written to
+	hash   string
+	index  *index
+	config Config
+	// sealed are the encrypted secret commands by note ID
+	sealed map[string]sealedCommand
 }
 
 // Config is a struct that represents YAML storage config
 type Config struct {
 	Name string
+	// Backups is the number of backups kept of the data file, 0 disables them
+	Backups int
+	// BackupMaxAge removes older backups when set, the latest one is always kept
+	BackupMaxAge time.Duration
+	// SyncRemote is the git remote, name or URL, the notes are synced with. Changes are committed when set.
+	SyncRemote string
+	SyncBranch string
+	// Encrypt encrypts the whole data file with the Cipher key
+	Encrypt bool
+	// Cipher encrypts the data file and the commands of secret notes, it's nil when no key is configured
+	Cipher *crypt.Cipher
 }
 
 // Initialize the YAML storage
-func Initialize(config *Config) *Yaml {
+func Initialize(config *Config) (*Yaml, error) {
+	if config.Encrypt && config.Cipher == nil {
+		return nil, fmt.Errorf("the notes file %s is encrypted: %w", config.Name, crypt.ErrNoKey)
+	}
+
 	// check if file exists, if not create it
 	f, err := os.OpenFile(config.Name, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
-		log.Fatal(err)
+		return nil, errdefs.Mark(fmt.Errorf("error while opening notes file: %w", err), errdefs.ErrStorageUnavailable)
 	}
 	defer f.Close()
 
-	f.Read([]byte{})
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errdefs.Mark(fmt.Errorf("error while reading notes file: %w", err), errdefs.ErrStorageUnavailable)
+	}
 
-	var notes []Note
+	y := &Yaml{File: f, config: *config}
 
-	// check if file siza > 0, if so read file and unmarshal it to Notes struct
-	if fi, _ := f.Stat(); fi.Size() > 0 {
-		if err := yaml.NewDecoder(f).Decode(&notes); err != nil {
+	if err := y.load(data); err != nil {
+		return nil, errdefs.Mark(fmt.Errorf("error while reading notes file %s: %w", config.Name, err), errdefs.ErrStorageUnavailable)
+	}
+
+	// encrypt or decrypt the data file when the encryption setting changed
+	if len(data) > 0 && crypt.IsEncryptedFile(data) != config.Encrypt {
+		message := "Decrypt notes"
+		if config.Encrypt {
+			message = "Encrypt notes"
+		}
+		err := y.update(context.Background(), func(notes []Note) ([]Note, string, error) {
+			return notes, message, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return y, nil
+}
+
+// Load reads and parses the notes of a YAML data file, the commands of secret notes are decrypted with the cipher
+func Load(config *Config) ([]Note, error) {
+	data, err := os.ReadFile(config.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	y := &Yaml{config: *config}
+	notes, err := y.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return y.openAll(notes)
+}
+
+// Refresh reads the notes again when the data file was changed since, e.g. by another rmm process.
+// It's needed by long running processes, such as rmm serve.
+func (y *Yaml) Refresh(ctx context.Context) error {
+	data, err := os.ReadFile(y.File.Name())
+	if err != nil && !os.IsNotExist(err) {
+		return errdefs.Mark(fmt.Errorf("error while reading notes file: %w", err), errdefs.ErrStorageUnavailable)
+	}
+	if hashData(data) == y.hash {
+		return nil
+	}
+
+	if err := y.load(data); err != nil {
+		return errdefs.Mark(fmt.Errorf("error while reading notes file: %w", err), errdefs.ErrStorageUnavailable)
+	}
+	return nil
+}
+
+// load sets the notes and their index from the content of the data file. The notes are read from the
+// sidecar index file when it was built from the same content, parsing the data file is much slower.
+func (y *Yaml) load(data []byte) error {
+	hash := hashData(data)
+	if !y.config.Encrypt {
+		if idx, notes := readIndex(y.File.Name(), hash); idx != nil {
+			y.Notes, y.hash, y.index = notes, hash, idx
 			return nil
 		}
 	}
 
-	return &Yaml{File: f, Notes: notes}
+	var notes []Note
+	if len(data) > 0 {
+		var err error
+		if notes, err = y.decode(data); err != nil {
+			return err
+		}
+	}
+	y.Notes, y.hash = notes, hash
+	y.index = buildIndex(notes, hash)
+	y.saveIndex()
+	return nil
+}
+
+// Close releases the YAML storage, the file is only open while reading or writing
+func (y *Yaml) Close(ctx context.Context) error {
+	return nil
 }
 
 // Insert inserts a new note to YAML storage
-func (y *Yaml) Insert(note interface{}) error {
+func (y *Yaml) Insert(ctx context.Context, note interface{}) error {
+	_, err := y.Create(ctx, note)
+	return err
+}
+
+// Create inserts a new note to YAML storage and returns its ID
+func (y *Yaml) Create(ctx context.Context, note interface{}) (string, error) {
 	newNote := note.(Note)
+	if newNote.Secret && y.config.Cipher == nil {
+		return "", fmt.Errorf("cannot add a secret note: %w", crypt.ErrNoKey)
+	}
 
-	// check if command already exists
-	// if it does, update tags and description
-	for i, n := range y.Notes {
-		if n.Command == newNote.Command {
-			y.Notes[i].Tags = newNote.Tags
-			y.Notes[i].Description = newNote.Description
-			return y.save()
+	id := ""
+	err := y.update(ctx, func(notes []Note) ([]Note, string, error) {
+		notes, message := insert(notes, newNote)
+		id = notes[len(notes)-1].ID
+		return notes, message, nil
+	})
+	return id, err
+}
+
+// InsertAll inserts several notes at once, with a single write of the data file
+func (y *Yaml) InsertAll(ctx context.Context, items interface{}) error {
+	newNotes := items.([]Note)
+	for _, newNote := range newNotes {
+		if newNote.Secret && y.config.Cipher == nil {
+			return fmt.Errorf("cannot add a secret note: %w", crypt.ErrNoKey)
 		}
 	}
 
-	// if it doesn't, create new one
-	// generate new id
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-	newNote.ID = strconv.Itoa(rand.Intn(1000000))
+	return y.update(ctx, func(notes []Note) ([]Note, string, error) {
+		for _, newNote := range newNotes {
+			notes, _ = insert(notes, newNote)
+		}
+		return notes, fmt.Sprintf("Add %d notes", len(newNotes)), nil
+	})
+}
 
-	// append new note to notes
-	y.Notes = append(y.Notes, newNote)
+// insert adds the note to the notes and returns the commit message of the change.
+// Duplicate commands are checked by NoteService.Create, they may be added on purpose.
+func insert(notes []Note, newNote Note) ([]Note, string) {
+	newNote.ID = newID(notes)
+	return append(notes, newNote), fmt.Sprintf("Add note %s: %s", newNote.ID, newNote.title())
+}
 
-	return y.save()
+// Update replaces the note with the same ID
+func (y *Yaml) Update(ctx context.Context, item interface{}) error {
+	return y.Merge(ctx, item, nil)
 }
 
+// Merge replaces the note with the same ID and deletes the notes merged into it, in a single change
+func (y *Yaml) Merge(ctx context.Context, item interface{}, ids []string) error {
+	updated := item.(Note)
+	if updated.Secret && y.config.Cipher == nil {
+		return fmt.Errorf("cannot save a secret note: %w", crypt.ErrNoKey)
+	}
+
+	return y.update(ctx, func(notes []Note) ([]Note, string, error) {
+		if _, err := find(notes, updated.ID); err != nil {
+			return nil, "", err
+		}
+
+		kept := []Note{}
+		for _, note := range notes {
+			switch {
+			case note.ID == updated.ID:
+				kept = append(kept, updated)
+			case !slices.Contains(ids, note.ID):
+				kept = append(kept, note)
+			}
+		}
+
+		if len(ids) == 0 {
+			return kept, fmt.Sprintf("Update note %s: %s", updated.ID, updated.title()), nil
+		}
+		return kept, fmt.Sprintf("Merge notes %s into %s: %s", strings.Join(ids, ", "), updated.ID, updated.title()), nil
+	})
+}
+
+// newID returns a random ID not used by the notes
+func newID(notes []Note) string {
+	used := make(map[string]bool, len(notes))
+	for _, note := range notes {
+		used[note.ID] = true
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		if id := strconv.Itoa(r.Intn(1000000)); !used[id] {
+			return id
+		}
+	}
+}
+
+// withLock runs fn while holding the lock of the data file,
+// so concurrent rmm processes don't lose each other's changes
+func (y *Yaml) withLock(fn func() error) error {
+	lock, err := os.OpenFile(lockFileName(y.File.Name()), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return errdefs.Mark(fmt.Errorf("error while locking notes file: %w", err), errdefs.ErrStorageUnavailable)
+	}
+	defer lock.Close()
+
+	if err := lockFile(lock); err != nil {
+		return errdefs.Mark(fmt.Errorf("error while locking notes file: %w", err), errdefs.ErrStorageUnavailable)
+	}
+	defer unlockFile(lock)
+
+	return fn()
+}
+
+// update changes the notes while holding the lock of the data file. If the file changed since it
+// was loaded the change is applied to the notes on disk. The file is backed up before it's changed,
+// and when syncing is configured the change is committed with the message returned by change.
+func (y *Yaml) update(ctx context.Context, change func(notes []Note) ([]Note, string, error)) error {
+	return y.withLock(func() error {
+		data, err := os.ReadFile(y.File.Name())
+		if err != nil && !os.IsNotExist(err) {
+			return errdefs.Mark(fmt.Errorf("error while reading notes file: %w", err), errdefs.ErrStorageUnavailable)
+		}
+
+		// the notes and their index are read again, even when the change fails
+		if hashData(data) != y.hash {
+			if err := y.load(data); err != nil {
+				return errdefs.Mark(fmt.Errorf("error while reading notes file: %w", err), errdefs.ErrStorageUnavailable)
+			}
+		}
+
+		notes, message, err := change(append([]Note(nil), y.Notes...))
+		if err != nil {
+			return err
+		}
+
+		if y.config.Backups > 0 && len(data) > 0 {
+			if _, err := y.backup(data); err != nil {
+				return err
+			}
+		}
+
+		y.Notes = notes
+		if err := y.save(); err != nil {
+			return err
+		}
+
+		// the change is saved, a failed commit is retried by the next sync
+		if message != "" {
+			y.commit(ctx, message)
+		}
+		return nil
+	})
+}
+
+// save writes the notes and their index, it must be called by update while holding the lock
 func (y *Yaml) save() error {
-	data, err := yaml.Marshal(y.Notes)
+	data, err := y.encode(y.Notes)
 	if err != nil {
-		return errors.New("error while marshalling notes")
+		return err
 	}
 
-	if err := os.WriteFile(y.File.Name(), data, 0644); err != nil {
-		return errors.New("error while writing notes to file")
+	if err := writeFile(y.File.Name(), data); err != nil {
+		return errdefs.Mark(fmt.Errorf("error while writing notes to file: %w", err), errdefs.ErrStorageUnavailable)
 	}
+
+	y.hash = hashData(data)
+	y.index = buildIndex(y.Notes, y.hash)
+	y.saveIndex()
 	return nil
 }
 
-// Get returns a note by id
-func (y *Yaml) Get(id string) (interface{}, error) {
-	for _, note := range y.Notes {
+// saveIndex writes the index to the sidecar file, or removes it when the notes can't be kept on disk
+func (y *Yaml) saveIndex() {
+	if persistIndex(&y.config, y.Notes) {
+		y.index.save(y.File.Name(), y.Notes)
+	} else {
+		os.Remove(indexFile(y.File.Name()))
+	}
+}
+
+// writeFile replaces the file content atomically: the data is written and synced to a temporary
+// file which is then renamed over the file, so a crash never leaves it partially written
+func writeFile(name string, data []byte) error {
+	// replace the target of a symbolic link instead of the link
+	if target, err := filepath.EvalSymlinks(name); err == nil {
+		name = target
+	}
+
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(name); err == nil {
+		perm = fi.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if err := writeAndSync(f, data, perm); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Rename(f.Name(), name); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func writeAndSync(f *os.File, data []byte, perm os.FileMode) error {
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// lockFileName returns the file locked while the data file is changed,
+// the data file itself is replaced on every write so it can't hold the lock
+func lockFileName(name string) string {
+	return name + ".lock"
+}
+
+// getIndex returns the index of the notes, building it when it wasn't built from the same data file content
+func (y *Yaml) getIndex() *index {
+	if y.index == nil || y.index.Hash != y.hash {
+		y.index = buildIndex(y.Notes, y.hash)
+	}
+	return y.index
+}
+
+// find returns the position of the note with the ID, or of the only note whose ID starts with it
+func find(notes []Note, id string) (int, error) {
+	if id == "" {
+		return -1, errdefs.Mark(errors.New("the note ID is empty"), errdefs.ErrNotFound)
+	}
+
+	exact, found := []int{}, []int{}
+	for i, note := range notes {
 		if note.ID == id {
-			return note, nil
+			exact = append(exact, i)
+		} else if strings.HasPrefix(note.ID, id) {
+			found = append(found, i)
 		}
 	}
+	if len(exact) > 0 {
+		found = exact
+	}
+
+	switch len(found) {
+	case 0:
+		return -1, fmt.Errorf("note %s %w", id, errdefs.ErrNotFound)
+	case 1:
+		return found[0], nil
+	}
+	return -1, fmt.Errorf("%w %s, it matches %d notes", errdefs.ErrAmbiguousID, id, len(found))
+}
 
-	return nil, nil
+// Get returns a note by id, or by the start of its id when only one note matches
+func (y *Yaml) Get(ctx context.Context, id string) (interface{}, error) {
+	i, err := find(y.Notes, id)
+	if err != nil {
+		return nil, err
+	}
+	return y.open(y.Notes[i])
 }
 
 // GetByTags returns notes by tags
-func (y *Yaml) GetByTags(tags []string) (interface{}, error) {
-	var filteredNotes []Note
+func (y *Yaml) GetByTags(ctx context.Context, tags []string) (interface{}, error) {
+	positions := y.getIndex().byTags(tags)
+	filteredNotes := make([]Note, len(positions))
+	for i, pos := range positions {
+		filteredNotes[i] = y.Notes[pos]
+	}
+	return y.openAll(filteredNotes)
+}
+
+// GetByCommand returns the notes with the command, secret commands are decrypted to be compared
+func (y *Yaml) GetByCommand(ctx context.Context, command string) (interface{}, error) {
+	if err := y.openSecrets(); err != nil {
+		return nil, err
+	}
+
+	positions := y.getIndex().Commands[command]
+	notes := make([]Note, len(positions))
+	for i, pos := range positions {
+		notes[i] = y.Notes[pos]
+	}
+	return notes, nil
+}
+
+// getByTagsScan is GetByTags without the index
+func (y *Yaml) getByTagsScan(tags []string) []Note {
+	filteredNotes := []Note{}
 	for _, note := range y.Notes {
-		for _, tag := range tags {
-			for _, noteTag := range note.Tags {
-				if noteTag == tag {
-					filteredNotes = append(filteredNotes, note)
-				}
-			}
+		if hasAnyTag(note, tags) {
+			filteredNotes = append(filteredNotes, note)
 		}
 	}
-
-	return filteredNotes, nil
+	return filteredNotes
 }
 
 // GetAll returns all notes
-func (y *Yaml) GetAll() (interface{}, error) {
-	return y.Notes, nil
+func (y *Yaml) GetAll(ctx context.Context) (interface{}, error) {
+	return y.openAll(y.Notes)
 }
 
 // GetTags returns all available tags
-func (y *Yaml) GetTags() ([]string, error) {
+func (y *Yaml) GetTags(ctx context.Context) ([]string, error) {
 	var tags []string
 	for _, note := range y.Notes {
 		for _, tag := range note.Tags {
@@ -137,112 +497,103 @@ func (y *Yaml) GetTags() ([]string, error) {
 	return tags, nil
 }
 
-// Delete deletes a note by id
-func (y *Yaml) Delete(id string) error {
-	for i, note := range y.Notes {
-		if note.ID == id {
-			y.Notes = append(y.Notes[:i], y.Notes[i+1:]...)
-			return y.save()
+// IncrementUses counts a use of a note, uses rank search results
+func (y *Yaml) IncrementUses(ctx context.Context, id string) error {
+	return y.update(ctx, func(notes []Note) ([]Note, string, error) {
+		i, err := find(notes, id)
+		if err != nil {
+			return nil, "", err
 		}
-	}
-	return nil
+		notes[i].Uses++
+		// uses aren't committed on their own, they're included in the next commit
+		return notes, "", nil
+	})
+}
+
+// Delete deletes a note by id, or by the start of its id when only one note matches
+func (y *Yaml) Delete(ctx context.Context, id string) error {
+	return y.update(ctx, func(notes []Note) ([]Note, string, error) {
+		i, err := find(notes, id)
+		if err != nil {
+			return nil, "", err
+		}
+		note := notes[i]
+		return append(notes[:i], notes[i+1:]...), fmt.Sprintf("Delete note %s: %s", note.ID, note.title()), nil
+	})
 }
 
 // DeleteByTags deletes notes by tags
-func (y *Yaml) DeleteByTags(tags []string) error {
-	for i, note := range y.Notes {
-		for _, tag := range tags {
-			for _, noteTag := range note.Tags {
-				if noteTag == tag {
-					y.Notes = append(y.Notes[:i], y.Notes[i+1:]...)
-				}
+func (y *Yaml) DeleteByTags(ctx context.Context, tags []string) error {
+	return y.update(ctx, func(notes []Note) ([]Note, string, error) {
+		var keptNotes []Note
+		for _, note := range notes {
+			if !hasAnyTag(note, tags) {
+				keptNotes = append(keptNotes, note)
 			}
 		}
-	}
-	return y.save()
+		message := fmt.Sprintf("Delete %d notes tagged %s", len(notes)-len(keptNotes), strings.Join(tags, ", "))
+		return keptNotes, message, nil
+	})
 }
 
-// Search returns notes by search words
-func (y *Yaml) Search(searchWords []string, searchLocations []string) (interface{}, error) {
-	var filteredNotes []Note
-	var notes []Note
-	var err error
-
-	for _, searchLocation := range searchLocations {
-		switch searchLocation {
-		case "command":
-			notes, err = y.SearchInCommand(searchWords)
-		case "description":
-			notes, err = y.SearchInDescription(searchWords)
-		case "tags":
-			notes, err = y.SearchInTags(searchWords)
+// Search returns notes matching the query, best matches first.
+// Exact matches are looked up in the index, fuzzy queries only rank all notes when nothing matches exactly.
+// Secret commands are decrypted when the query searches commands.
+func (y *Yaml) Search(ctx context.Context, q *search.Query) (interface{}, error) {
+	if searchesCommands(q) {
+		if err := y.openSecrets(); err != nil {
+			return nil, err
 		}
+	}
 
-		if err != nil {
-			color.Red("Error while getting notes by tags: %s", err)
-		}
+	idx := y.getIndex()
+	exact := *q
+	exact.Fuzzy = false
 
-		filteredNotes = y.appendSearchResults(filteredNotes, notes)
+	candidates := idx.candidates(q)
+	docs := make([]search.Document, len(candidates))
+	for i, pos := range candidates {
+		docs[i] = idx.documents[pos]
 	}
-	return filteredNotes, nil
-}
 
-// SearchInTags returns notes by search word in tags
-func (y *Yaml) SearchInTags(searchWords []string) ([]Note, error) {
 	var filteredNotes []Note
-	for _, note := range y.Notes {
-		for _, noteTag := range note.Tags {
-			for _, searchWord := range searchWords {
-				if strings.Contains(noteTag, searchWord) {
-					filteredNotes = append(filteredNotes, note)
-				}
-			}
-		}
+	for _, ranked := range search.Rank(&exact, docs) {
+		note := y.Notes[candidates[ranked.Index]]
+		note.Score = ranked.Score
+		filteredNotes = append(filteredNotes, note)
 	}
-	return filteredNotes, nil
-}
 
-// SearchInCommand returns notes by search word in command
-func (y *Yaml) SearchInCommand(searchWords []string) ([]Note, error) {
-	var filteredNotes []Note
-	for _, note := range y.Notes {
-		for _, searchWord := range searchWords {
-			if strings.Contains(note.Command, searchWord) {
-				filteredNotes = append(filteredNotes, note)
-			}
-		}
+	if len(filteredNotes) == 0 && q.Fuzzy {
+		filteredNotes = y.searchScan(q)
 	}
-	return filteredNotes, nil
+	return y.openAll(filteredNotes)
 }
 
-// SearchInDescription returns notes by search word in description
-func (y *Yaml) SearchInDescription(searchWords []string) ([]Note, error) {
-	var filteredNotes []Note
-	for _, note := range y.Notes {
-		for _, searchWord := range searchWords {
-			if strings.Contains(note.Description, searchWord) {
-				filteredNotes = append(filteredNotes, note)
+func searchesCommands(q *search.Query) bool {
+	for _, clause := range q.Clauses {
+		for _, term := range clause.Terms {
+			if slices.Contains(term.Fields, "command") {
+				return true
 			}
 		}
 	}
-	return filteredNotes, nil
+	return false
 }
 
-func (y *Yaml) appendSearchResults(notes []Note, newNotes []Note) []Note {
+// searchScan ranks all notes against the query without the index
+func (y *Yaml) searchScan(q *search.Query) []Note {
 	var filteredNotes []Note
-	filteredNotes = notes
-
-	for _, newNote := range newNotes {
-		if !(resultContainsID(filteredNotes, newNote.ID)) {
-			filteredNotes = append(filteredNotes, newNote)
-		}
+	for _, ranked := range search.Rank(q, documents(y.Notes)) {
+		note := y.Notes[ranked.Index]
+		note.Score = ranked.Score
+		filteredNotes = append(filteredNotes, note)
 	}
 	return filteredNotes
 }
 
-func resultContainsID(notes []Note, searchID string) bool {
-	for _, item := range notes {
-		if item.ID == searchID {
+func hasAnyTag(note Note, tags []string) bool {
+	for _, tag := range tags {
+		if containsTag(note.Tags, tag) {
 			return true
 		}
 	}
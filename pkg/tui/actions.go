@@ -0,0 +1,333 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/clipboard"
+	"github.com/carloscastrojumo/remindme/pkg/duplicate"
+	"github.com/carloscastrojumo/remindme/pkg/output"
+	"github.com/carloscastrojumo/remindme/pkg/prompt"
+	"github.com/carloscastrojumo/remindme/pkg/runner"
+	"github.com/carloscastrojumo/remindme/pkg/secrets"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// dialogPage is the page of the dialog shown over the main page
+const dialogPage = "dialog"
+
+// copy copies the command of the selected note, a copied note counts as used
+func (u *UI) copy() {
+	note := u.selected()
+	if note == nil {
+		return
+	}
+	if err := clipboard.Copy(note.Command, u.clipboard); err != nil {
+		u.fail(fmt.Errorf("note %s not copied: %w", note.ID, err))
+		return
+	}
+	u.markUsed(note)
+	u.setStatus("[green]Note %s copied to the clipboard", note.ID)
+}
+
+// run runs the command of the selected note in the terminal, the interface is back once Enter is pressed
+func (u *UI) run() {
+	note := u.selected()
+	if note == nil {
+		return
+	}
+
+	var err error
+	u.app.Suspend(func() {
+		fmt.Printf("$ %s\n", note.Command)
+		err = runner.Command(u.ctx, note.Command, note.Shell, nil).Run()
+		if err != nil {
+			fmt.Printf("\n%s\n", err)
+		}
+		fmt.Print("\nPress Enter to go back to rmm ui")
+		bufio.NewReader(os.Stdin).ReadString('\n')
+	})
+
+	u.markUsed(note)
+	if err != nil {
+		u.setStatus("[yellow]Note %s failed: %s", note.ID, tview.Escape(err.Error()))
+		return
+	}
+	u.setStatus("[green]Note %s run", note.ID)
+}
+
+func (u *UI) markUsed(note *storage.Note) {
+	if err := u.service.MarkUsed(u.ctx, note.ID); err != nil {
+		u.fail(fmt.Errorf("error while counting the use of note %s: %w", note.ID, err))
+		return
+	}
+	u.reload(note.ID)
+}
+
+// remove removes the selected note once confirmed
+func (u *UI) remove() {
+	note := u.selected()
+	if note == nil {
+		return
+	}
+
+	text := fmt.Sprintf("Remove note %s?\n\n%s", note.ID, tview.Escape(firstLine(u.command(*note))))
+	u.choose(text, []string{"Remove", "Cancel"}, func(choice string) {
+		if choice != "Remove" {
+			return
+		}
+		if err := u.service.Remove(u.ctx, note.ID); err != nil {
+			u.fail(err)
+			return
+		}
+		u.reload("")
+		u.setStatus("[green]Note %s deleted", note.ID)
+	})
+}
+
+// retag replaces the tags of the selected note
+func (u *UI) retag() {
+	note := u.selected()
+	if note == nil {
+		return
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("Tags", strings.Join(note.Tags, ", "), 0, nil, nil)
+	form.AddButton("Save", func() {
+		note.Tags = parseTags(form.GetFormItem(0).(*tview.InputField).GetText())
+		u.closeDialog()
+		u.update(*note)
+	})
+	form.AddButton("Cancel", u.closeDialog)
+	form.SetCancelFunc(u.closeDialog)
+	form.SetBorder(true).SetTitle(fmt.Sprintf(" Tags of note %s, separated by commas ", note.ID))
+	u.showDialog(form, 70, 7)
+}
+
+// edit edits the note, a new one is added when it's nil, with the selected tag.
+// The command of a secret note is masked unless revealed, it's kept when the mask isn't changed.
+func (u *UI) edit(note *storage.Note) {
+	edited := storage.Note{}
+	title := " Add a note "
+	if u.tag != "" {
+		edited.Tags = []string{u.tag}
+	}
+	if note != nil {
+		edited = *note
+		title = fmt.Sprintf(" Edit note %s ", note.ID)
+	}
+
+	form := tview.NewForm()
+	form.AddTextArea("Command", u.command(edited), 0, 5, 0, nil)
+	command := form.GetFormItemByLabel("Command").(*tview.TextArea)
+	form.AddInputField("Description", edited.Description, 0, nil, nil)
+	form.AddInputField("Tags", strings.Join(edited.Tags, ", "), 0, nil, nil)
+	form.AddInputField("Shell", edited.Shell, 20, nil, nil)
+	form.AddCheckbox("Secret", edited.Secret, nil)
+	text := func(label string) string {
+		return form.GetFormItemByLabel(label).(*tview.InputField).GetText()
+	}
+
+	form.AddButton("Save", func() {
+		if strings.TrimSpace(command.GetText()) == "" {
+			u.setStatus("[yellow]The command is empty")
+			return
+		}
+		if !(edited.Secret && command.GetText() == output.SecretMask) {
+			edited.Command = command.GetText()
+		}
+		edited.Description = strings.TrimSpace(text("Description"))
+		edited.Tags = parseTags(text("Tags"))
+		edited.Shell = strings.TrimSpace(text("Shell"))
+		edited.Secret = form.GetFormItemByLabel("Secret").(*tview.Checkbox).IsChecked()
+
+		u.closeDialog()
+		u.checkSecrets(edited, func(checked storage.Note) {
+			if note == nil {
+				u.add(checked)
+			} else {
+				u.update(checked)
+			}
+		})
+	})
+	form.AddButton("Editor", func() {
+		shell := strings.TrimSpace(text("Shell"))
+		var script string
+		var err error
+		u.app.Suspend(func() {
+			script, err = prompt.ForText(command.GetText(), runner.Extension(shell))
+		})
+		if err != nil {
+			u.fail(err)
+			return
+		}
+		command.SetText(script, true)
+	})
+	form.AddButton("Cancel", u.closeDialog)
+	form.SetCancelFunc(u.closeDialog)
+	form.SetBorder(true).SetTitle(title)
+	u.showDialog(form, 90, 19)
+}
+
+// checkSecrets asks what to do when the command of the note looks like it holds secrets, as 'rmm add' does
+func (u *UI) checkSecrets(note storage.Note, next func(storage.Note)) {
+	if note.Secret {
+		next(note)
+		return
+	}
+	findings := secrets.Scan(note.Command)
+	if len(findings) == 0 {
+		next(note)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("The command looks like it holds secrets:\n")
+	for _, f := range findings {
+		fmt.Fprintf(&b, "\n%s: %s", f.Kind, tview.Escape(f.Masked()))
+	}
+	u.choose(b.String(), []string{"Redact them", "Make it secret", "Keep it", "Cancel"}, func(choice string) {
+		switch choice {
+		case "Redact them":
+			note.Command = secrets.Redact(note.Command, findings)
+		case "Make it secret":
+			note.Secret = true
+		case "Keep it":
+		default:
+			u.setStatus("[yellow]Note not saved")
+			return
+		}
+		next(note)
+	})
+}
+
+// add adds the note, or merges it into a note with the same command when chosen
+func (u *UI) add(note storage.Note) {
+	duplicates := storage.FindDuplicates(u.notes, note.Command)
+	if len(duplicates) == 0 {
+		u.create(note)
+		return
+	}
+
+	d := duplicates[0]
+	text := fmt.Sprintf("The command is already in note %s.", d.Note.ID)
+	if d.Kind != duplicate.Exact {
+		text = fmt.Sprintf("The command is nearly the same as note %s, %s.", d.Note.ID, d.Kind)
+	}
+	merge := "Merge into " + d.Note.ID
+	u.choose(text, []string{merge, "Add it anyway", "Cancel"}, func(choice string) {
+		switch choice {
+		case merge:
+			if err := u.service.Update(u.ctx, storage.MergeNotes(d.Note, note)); err != nil {
+				u.fail(err)
+				return
+			}
+			u.reload(d.Note.ID)
+			u.setStatus("[green]Note merged into note %s", d.Note.ID)
+		case "Add it anyway":
+			u.create(note)
+		default:
+			u.setStatus("[yellow]Note not added")
+		}
+	})
+}
+
+// create adds the note, its duplicates were resolved by add
+func (u *UI) create(note storage.Note) {
+	created, err := u.service.CreateAnyway(u.ctx, note)
+	if err != nil {
+		u.fail(err)
+		return
+	}
+	u.reload(created.ID)
+	if created.ID == "" {
+		u.setStatus("[green]Note added")
+		return
+	}
+	u.setStatus("[green]Note %s added", created.ID)
+}
+
+func (u *UI) update(note storage.Note) {
+	if err := u.service.Update(u.ctx, note); err != nil {
+		u.fail(err)
+		return
+	}
+	u.reload(note.ID)
+	u.setStatus("[green]Note %s updated", note.ID)
+}
+
+func (u *UI) showHelp() {
+	text := `[::b]a[::-]    add a note
+[::b]e[::-]    edit the selected note, Editor opens $VISUAL or $EDITOR on the command
+[::b]d[::-]    delete it
+[::b]t[::-]    change its tags
+[::b]c[::-]    copy its command, also Enter
+[::b]r[::-]    run its command
+[::b]v[::-]    show or hide the commands of secret notes
+[::b]/[::-]    filter the notes with a query of rmm search, Esc clears the filter
+[::b]tab[::-]  switch between the tags and the notes
+[::b]q[::-]    quit, also Ctrl-C
+
+Press Enter or Esc to close.`
+	view := tview.NewTextView().SetDynamicColors(true).SetText(text)
+	view.SetDoneFunc(func(tcell.Key) {
+		u.closeDialog()
+	})
+	view.SetBorder(true).SetTitle(" Keys ")
+	u.showDialog(view, 84, 15)
+}
+
+// selected returns the selected note, with a message when no note is shown
+func (u *UI) selected() *storage.Note {
+	note := u.current()
+	if note == nil {
+		u.setStatus("[yellow]No note selected")
+	}
+	return note
+}
+
+// choose shows the text with a button per choice, done is called with the chosen one
+func (u *UI) choose(text string, choices []string, done func(choice string)) {
+	modal := tview.NewModal().SetText(text).AddButtons(choices)
+	modal.SetDoneFunc(func(_ int, choice string) {
+		u.closeDialog()
+		done(choice)
+	})
+	u.pages.AddPage(dialogPage, modal, false, true)
+	u.app.SetFocus(modal)
+}
+
+// showDialog shows the dialog centered over the main page
+func (u *UI) showDialog(dialog tview.Primitive, width int, height int) {
+	rows := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(dialog, height, 0, true).
+		AddItem(nil, 0, 1, false)
+	centered := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(rows, width, 0, true).
+		AddItem(nil, 0, 1, false)
+	u.pages.AddPage(dialogPage, centered, true, true)
+	u.app.SetFocus(dialog)
+}
+
+func (u *UI) closeDialog() {
+	u.pages.RemovePage(dialogPage)
+	u.app.SetFocus(u.noteList)
+}
+
+// parseTags reads tags separated by commas, without empty or repeated ones
+func parseTags(text string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(text, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" && !containsTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,394 @@
+// Package tui is the full-screen terminal interface of rmm ui, browsing and curating the notes
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/carloscastrojumo/remindme/pkg/logger"
+	"github.com/carloscastrojumo/remindme/pkg/output"
+	"github.com/carloscastrojumo/remindme/pkg/search"
+	"github.com/carloscastrojumo/remindme/pkg/storage"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// mainPage is the page of the sidebar, the notes and the detail pane, the dialogs are shown over it
+const mainPage = "main"
+
+const help = "[::b]a[::-] add  [::b]e[::-] edit  [::b]d[::-] delete  [::b]t[::-] retag  [::b]c[::-] copy  " +
+	"[::b]r[::-] run  [::b]v[::-] reveal  [::b]/[::-] filter  [::b]tab[::-] switch  [::b]?[::-] help  [::b]q[::-] quit"
+
+// ansi matches the color codes of the messages logged while the interface is shown
+var ansi = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// UI is the terminal interface over the notes of a note service
+type UI struct {
+	ctx       context.Context
+	service   *storage.NoteService
+	clipboard string // clipboard method copying the notes
+
+	app      *tview.Application
+	pages    *tview.Pages
+	tagList  *tview.List
+	filter   *tview.InputField
+	noteList *tview.List
+	detail   *tview.TextView
+	status   *tview.TextView
+
+	notes []storage.Note
+	tags  []string
+	// shown are the notes of the list, of the selected tag and matching the filter
+	shown []storage.Note
+	// tag is the selected tag, all the notes are shown when it's empty
+	tag string
+	// reveal shows the commands of secret notes
+	reveal bool
+	// rendering is set while the lists are filled, their changes are ignored
+	rendering bool
+}
+
+// New returns the interface over the notes of the service, notes are copied with the clipboard method.
+// Interrupting a note run with Ctrl-C doesn't cancel the storage requests of the interface.
+func New(ctx context.Context, service *storage.NoteService, clipboardMethod string) *UI {
+	u := &UI{
+		ctx:       context.WithoutCancel(ctx),
+		service:   service,
+		clipboard: clipboardMethod,
+		app:       tview.NewApplication(),
+		pages:     tview.NewPages(),
+		tagList:   tview.NewList(),
+		filter:    tview.NewInputField(),
+		noteList:  tview.NewList(),
+		detail:    tview.NewTextView(),
+		status:    tview.NewTextView(),
+	}
+
+	u.tagList.ShowSecondaryText(false).SetHighlightFullLine(true)
+	u.tagList.SetBorder(true).SetTitle(" Tags ")
+	u.tagList.SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+		if u.rendering {
+			return
+		}
+		u.tag = ""
+		if index > 0 {
+			u.tag = u.tags[index-1]
+		}
+		u.applyFilter("")
+	})
+	u.tagList.SetSelectedFunc(func(int, string, string, rune) {
+		u.app.SetFocus(u.noteList)
+	})
+
+	u.filter.SetLabel("Filter: ").SetPlaceholder("e.g. tag:k8s get pods, / to focus")
+	u.filter.SetChangedFunc(func(string) {
+		u.applyFilter(u.selectedID())
+	})
+	u.filter.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			u.filter.SetText("")
+		}
+		u.app.SetFocus(u.noteList)
+	})
+
+	u.noteList.SetHighlightFullLine(true).SetSecondaryTextColor(tcell.ColorGray)
+	u.noteList.SetBorder(true).SetTitle(" Notes ")
+	u.noteList.SetChangedFunc(func(int, string, string, rune) {
+		if !u.rendering {
+			u.showDetail()
+		}
+	})
+	u.noteList.SetSelectedFunc(func(int, string, string, rune) {
+		u.copy()
+	})
+
+	u.detail.SetDynamicColors(true).SetWrap(true)
+	u.detail.SetBorder(true).SetTitle(" Note ")
+
+	u.status.SetDynamicColors(true)
+
+	notes := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.filter, 1, 0, false).
+		AddItem(u.noteList, 0, 1, true)
+	panes := tview.NewFlex().
+		AddItem(u.tagList, 24, 0, false).
+		AddItem(notes, 0, 2, true).
+		AddItem(u.detail, 0, 2, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(panes, 0, 1, true).
+		AddItem(u.status, 1, 0, false).
+		AddItem(tview.NewTextView().SetDynamicColors(true).SetText(help), 1, 0, false)
+	u.pages.AddPage(mainPage, layout, true, true)
+
+	u.app.SetInputCapture(u.keys).EnableMouse(true)
+	return u
+}
+
+// Run shows the interface until it's quit, the messages logged meanwhile are shown in its status line
+func (u *UI) Run() error {
+	if err := u.load(); err != nil {
+		return err
+	}
+
+	logger.SetOutput(statusWriter{u})
+	defer logger.SetOutput(os.Stderr)
+	return u.app.SetRoot(u.pages, true).SetFocus(u.noteList).Run()
+}
+
+// keys handles the keys of the main page, they're typed in the filter and the dialogs as they are
+func (u *UI) keys(event *tcell.EventKey) *tcell.EventKey {
+	if front, _ := u.pages.GetFrontPage(); front != mainPage || u.filter.HasFocus() {
+		return event
+	}
+
+	switch event.Key() {
+	case tcell.KeyTab, tcell.KeyBacktab:
+		if u.tagList.HasFocus() {
+			u.app.SetFocus(u.noteList)
+		} else {
+			u.app.SetFocus(u.tagList)
+		}
+		return nil
+	case tcell.KeyRune:
+	default:
+		return event
+	}
+
+	switch event.Rune() {
+	case 'q':
+		u.app.Stop()
+	case '/':
+		u.app.SetFocus(u.filter)
+	case 'a':
+		u.edit(nil)
+	case 'e':
+		if note := u.selected(); note != nil {
+			u.edit(note)
+		}
+	case 'd':
+		u.remove()
+	case 't':
+		u.retag()
+	case 'c', 'y':
+		u.copy()
+	case 'r':
+		u.run()
+	case 'v':
+		u.reveal = !u.reveal
+		u.applyFilter(u.selectedID())
+	case 'j':
+		return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+	case 'k':
+		return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+	case '?':
+		u.showHelp()
+	default:
+		return event
+	}
+	return nil
+}
+
+// load reads the notes and tags again, e.g. after a change
+func (u *UI) load() error {
+	if err := u.service.Refresh(u.ctx); err != nil {
+		return err
+	}
+	notes, err := u.service.Notes(u.ctx)
+	if err != nil {
+		return err
+	}
+	tags, err := u.service.GetTags(u.ctx)
+	if err != nil {
+		return err
+	}
+	sort.Strings(tags)
+
+	u.notes, u.tags = notes, tags
+	u.renderTags()
+	u.applyFilter(u.selectedID())
+	return nil
+}
+
+// reload reads the notes again and selects the note with the ID, the error is shown in the status line
+func (u *UI) reload(id string) {
+	if err := u.load(); err != nil {
+		u.fail(err)
+		return
+	}
+	if id != "" {
+		u.applyFilter(id)
+	}
+}
+
+func (u *UI) renderTags() {
+	u.rendering = true
+	defer func() { u.rendering = false }()
+
+	u.tagList.Clear()
+	u.tagList.AddItem("All notes", "", 0, nil)
+	selected := 0
+	for i, tag := range u.tags {
+		u.tagList.AddItem(tview.Escape(tag), "", 0, nil)
+		if tag == u.tag {
+			selected = i + 1
+		}
+	}
+	if selected == 0 {
+		u.tag = ""
+	}
+	u.tagList.SetCurrentItem(selected)
+}
+
+// applyFilter lists the notes of the selected tag matching the filter, and selects the note with the ID.
+// The filter is a query of 'rmm search', exact matches first and fuzzy ones when nothing matches exactly.
+func (u *UI) applyFilter(id string) {
+	notes := []storage.Note{}
+	for _, note := range u.notes {
+		if u.tag == "" || containsTag(note.Tags, u.tag) {
+			notes = append(notes, note)
+		}
+	}
+
+	if input := strings.TrimSpace(u.filter.GetText()); input != "" {
+		query, err := search.Parse(input, nil, false)
+		if err != nil {
+			u.setStatus("[yellow]Filter: %s", tview.Escape(err.Error()))
+			return
+		}
+		notes = rank(query, notes)
+	}
+	u.shown = notes
+
+	u.rendering = true
+	u.noteList.Clear()
+	selected := 0
+	for i, note := range notes {
+		u.noteList.AddItem(tview.Escape(firstLine(u.command(note))), tview.Escape(summary(note)), 0, nil)
+		if note.ID == id {
+			selected = i
+		}
+	}
+	u.noteList.SetCurrentItem(selected)
+	u.rendering = false
+
+	u.noteList.SetTitle(fmt.Sprintf(" Notes (%d) ", len(notes)))
+	u.showDetail()
+}
+
+// rank returns the notes matching the query, best first
+func rank(query *search.Query, notes []storage.Note) []storage.Note {
+	docs := make([]search.Document, len(notes))
+	for i, note := range notes {
+		docs[i] = search.Document{Command: note.Command, Description: note.Description, Tags: note.Tags, Uses: note.Uses}
+	}
+
+	ranked := search.Rank(query, docs)
+	if len(ranked) == 0 {
+		query.Fuzzy = true
+		ranked = search.Rank(query, docs)
+	}
+
+	found := []storage.Note{}
+	for _, r := range ranked {
+		found = append(found, notes[r.Index])
+	}
+	return found
+}
+
+func (u *UI) showDetail() {
+	note := u.current()
+	if note == nil {
+		u.detail.SetText("[gray]No notes found")
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[::b]ID[::-]          %s\n", note.ID)
+	fmt.Fprintf(&b, "[::b]Tags[::-]        %s\n", tview.Escape(strings.Join(note.Tags, ", ")))
+	if note.Shell != "" {
+		fmt.Fprintf(&b, "[::b]Shell[::-]       %s\n", tview.Escape(note.Shell))
+	}
+	fmt.Fprintf(&b, "[::b]Uses[::-]        %d\n", note.Uses)
+	if note.Secret {
+		fmt.Fprintf(&b, "[::b]Secret[::-]      yes, v shows the command\n")
+	}
+	if note.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", tview.Escape(note.Description))
+	}
+	fmt.Fprintf(&b, "\n[green]%s[-]", tview.Escape(u.command(*note)))
+
+	u.detail.SetText(b.String()).ScrollToBeginning()
+}
+
+// current returns the selected note, nil when no note is shown
+func (u *UI) current() *storage.Note {
+	i := u.noteList.GetCurrentItem()
+	if i < 0 || i >= len(u.shown) {
+		return nil
+	}
+	note := u.shown[i]
+	return &note
+}
+
+func (u *UI) selectedID() string {
+	if note := u.current(); note != nil {
+		return note.ID
+	}
+	return ""
+}
+
+// command returns the command of the note as shown, masked when the note is secret unless revealed
+func (u *UI) command(note storage.Note) string {
+	if note.Secret && !u.reveal {
+		return output.SecretMask
+	}
+	return note.Command
+}
+
+func (u *UI) setStatus(format string, a ...interface{}) {
+	u.status.SetText(fmt.Sprintf(format, a...))
+}
+
+func (u *UI) fail(err error) {
+	u.setStatus("[red]Error: %s", tview.Escape(err.Error()))
+}
+
+// statusWriter shows the messages logged in the status line
+type statusWriter struct {
+	u *UI
+}
+
+func (w statusWriter) Write(p []byte) (int, error) {
+	w.u.status.SetText(tview.Escape(strings.TrimSpace(ansi.ReplaceAllString(string(p), ""))))
+	return len(p), nil
+}
+
+// summary is the second line of a note in the list, its description and tags
+func summary(note storage.Note) string {
+	tags := "[" + strings.Join(note.Tags, ", ") + "]"
+	if note.Description == "" {
+		return tags
+	}
+	return note.Description + "  " + tags
+}
+
+func firstLine(text string) string {
+	line, rest, found := strings.Cut(text, "\n")
+	if found && strings.TrimSpace(rest) != "" {
+		return line + " …"
+	}
+	return line
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,307 @@
+"use strict";
+
+// The web UI of rmm serve, it lists the notes grouped by tags as 'rmm list' does and
+// changes them through the API. Note contents are only ever set as text, never as HTML.
+
+const tokenKey = "remindme.token";
+
+const state = {
+  tag: "", // selected tag, all the notes when empty
+  query: "",
+  editing: null, // note changed by the editor, null when adding one
+};
+
+const $ = (id) => document.getElementById(id);
+
+// api sends a request to the API and returns the decoded body, it asks for the token when it's refused
+async function api(method, path, body) {
+  const headers = { Accept: "application/json" };
+  const token = localStorage.getItem(tokenKey);
+  if (token) {
+    headers.Authorization = "Bearer " + token;
+  }
+  if (body !== undefined) {
+    headers["Content-Type"] = "application/json";
+  }
+
+  const resp = await fetch(path, { method, headers, body: body === undefined ? undefined : JSON.stringify(body) });
+  if (resp.status === 401) {
+    await login();
+    return api(method, path, body);
+  }
+  if (!resp.ok) {
+    let message = resp.status + " " + resp.statusText;
+    let code = "";
+    try {
+      const body = await resp.json();
+      message = body.error || message;
+      code = body.code || "";
+    } catch (e) {
+      // not a JSON error, keep the status
+    }
+    const error = new Error(message);
+    error.code = code;
+    throw error;
+  }
+  return resp.status === 204 ? null : resp.json();
+}
+
+// login asks for the token and keeps it in the browser
+function login() {
+  const dialog = $("login");
+  dialog.querySelector("form").reset();
+  return new Promise((resolve, reject) => {
+    dialog.addEventListener("close", () => {
+      if (dialog.returnValue !== "save") {
+        reject(new Error("the server needs a token"));
+        return;
+      }
+      localStorage.setItem(tokenKey, dialog.querySelector("[name=token]").value.trim());
+      resolve();
+    }, { once: true });
+    dialog.returnValue = "";
+    dialog.showModal();
+  });
+}
+
+function status(message, error) {
+  const el = $("status");
+  el.textContent = message || "";
+  el.classList.toggle("error", Boolean(error));
+}
+
+function el(tag, className, text) {
+  const e = document.createElement(tag);
+  if (className) {
+    e.className = className;
+  }
+  if (text !== undefined) {
+    e.textContent = text;
+  }
+  return e;
+}
+
+function button(text, title, onclick) {
+  const b = el("button", "", text);
+  b.type = "button";
+  b.title = title;
+  b.addEventListener("click", onclick);
+  return b;
+}
+
+async function load() {
+  try {
+    const params = new URLSearchParams();
+    if (state.query) {
+      params.set("q", state.query);
+    }
+    if (state.tag) {
+      params.set("tag", state.tag);
+    }
+    const [groups, tags] = await Promise.all([api("GET", "/api/groups?" + params), api("GET", "/api/tags")]);
+    renderTags(tags);
+    renderGroups(groups);
+    status(groups.length === 0 ? "No notes found" : "");
+  } catch (e) {
+    status(e.message, true);
+  }
+}
+
+function renderTags(tags) {
+  const nav = $("tags");
+  nav.replaceChildren();
+  if (state.tag && !tags.includes(state.tag)) {
+    state.tag = "";
+  }
+  for (const tag of [...tags].sort()) {
+    const chip = button(tag, "Only show the notes tagged " + tag, () => {
+      state.tag = state.tag === tag ? "" : tag;
+      load();
+    });
+    chip.classList.add("tag");
+    chip.setAttribute("aria-pressed", String(state.tag === tag));
+    nav.append(chip);
+  }
+}
+
+function renderGroups(groups) {
+  const main = $("notes");
+  main.replaceChildren();
+  for (const group of groups) {
+    const section = el("section", "group");
+    const title = el("h2");
+    for (const tag of group.tags) {
+      title.append(el("span", "tag", tag));
+    }
+    section.append(title);
+    for (const note of group.notes) {
+      section.append(renderNote(note));
+    }
+    main.append(section);
+  }
+}
+
+function renderNote(note) {
+  const article = el("article", "note");
+
+  const command = el("pre", note.secret ? "command secret" : "command");
+  command.append(el("code", "", note.command));
+  article.append(command);
+
+  if (note.description) {
+    article.append(el("p", "description", note.description));
+  }
+
+  const meta = el("p", "meta");
+  meta.append(el("span", "id", note.id));
+  if (note.shell) {
+    meta.append(el("span", "", note.shell));
+  }
+  if (note.uses) {
+    meta.append(el("span", "", note.uses === 1 ? "used once" : "used " + note.uses + " times"));
+  }
+  article.append(meta);
+
+  const actions = el("div", "actions");
+  actions.append(
+    button("Copy", "Copy the command", () => copy(note)),
+    button("Edit", "Edit the note", () => edit(note)),
+    button("Delete", "Remove the note", () => remove(note)),
+  );
+  article.append(actions);
+  return article;
+}
+
+// copy copies the command, secret notes are read again with their command revealed
+async function copy(note) {
+  try {
+    let command = note.command;
+    if (note.secret) {
+      command = (await api("GET", "/api/notes/" + encodeURIComponent(note.id) + "?reveal=true")).command;
+    }
+    await writeClipboard(command);
+    await api("POST", "/api/notes/" + encodeURIComponent(note.id) + "/uses");
+    status("Note " + note.id + " copied to the clipboard");
+  } catch (e) {
+    status("Could not copy the note: " + e.message, true);
+  }
+}
+
+// writeClipboard falls back to a hidden text area when the clipboard API isn't allowed, e.g. over plain http
+async function writeClipboard(text) {
+  if (navigator.clipboard && window.isSecureContext) {
+    return navigator.clipboard.writeText(text);
+  }
+  const area = el("textarea");
+  area.value = text;
+  area.setAttribute("readonly", "");
+  area.className = "offscreen";
+  document.body.append(area);
+  area.select();
+  const ok = document.execCommand("copy");
+  area.remove();
+  if (!ok) {
+    throw new Error("no clipboard available");
+  }
+}
+
+function edit(note) {
+  state.editing = note;
+  const dialog = $("editor");
+  const form = dialog.querySelector("form");
+  form.reset();
+  $("editor-title").textContent = note ? "Edit note " + note.id : "Add a note";
+  form.querySelector(".error").textContent = "";
+  if (note) {
+    form.command.value = note.command;
+    form.description.value = note.description || "";
+    form.tags.value = (note.tags || []).join(", ");
+    form.shell.value = note.shell || "";
+    form.secret.checked = Boolean(note.secret);
+  } else if (state.tag) {
+    form.tags.value = state.tag;
+  }
+  dialog.showModal();
+}
+
+// save sends the note of the editor, a secret note sent with its masked command keeps its command.
+// A note with the command of another note is merged into it once confirmed.
+async function save(form) {
+  const note = {
+    command: form.command.value,
+    description: form.description.value.trim(),
+    tags: form.tags.value.split(",").map((tag) => tag.trim()).filter((tag) => tag !== ""),
+    shell: form.shell.value.trim(),
+    secret: form.secret.checked,
+  };
+  if (state.editing) {
+    await api("PUT", "/api/notes/" + encodeURIComponent(state.editing.id), note);
+    status("Note " + state.editing.id + " updated");
+  } else {
+    try {
+      const created = await api("POST", "/api/notes", note);
+      status(created.id ? "Note " + created.id + " added" : "Note added");
+    } catch (e) {
+      if (e.code !== "duplicate" || !confirm(e.message + ".\n\nMerge the note into it? Its tags are added.")) {
+        throw e;
+      }
+      const merged = await api("POST", "/api/notes?duplicate=merge", note);
+      status("Note merged into note " + merged.id);
+    }
+  }
+}
+
+async function remove(note) {
+  if (!confirm("Remove the note " + note.id + "?\n\n" + note.command)) {
+    return;
+  }
+  try {
+    await api("DELETE", "/api/notes/" + encodeURIComponent(note.id));
+    status("Note " + note.id + " removed");
+    await load();
+  } catch (e) {
+    status(e.message, true);
+  }
+}
+
+// readLinkToken keeps the token of the link printed by rmm serve, e.g. http://127.0.0.1:8080/#token=...,
+// and removes it from the address bar
+function readLinkToken() {
+  const token = new URLSearchParams(location.hash.slice(1)).get("token");
+  if (token) {
+    localStorage.setItem(tokenKey, token);
+    history.replaceState(null, "", location.pathname + location.search);
+  }
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  readLinkToken();
+
+  let timer;
+  $("search").addEventListener("input", (event) => {
+    clearTimeout(timer);
+    timer = setTimeout(() => {
+      state.query = event.target.value.trim();
+      load();
+    }, 200);
+  });
+
+  $("add").addEventListener("click", () => edit(null));
+
+  $("editor").querySelector("form").addEventListener("submit", async (event) => {
+    if (event.submitter && event.submitter.value !== "save") {
+      return;
+    }
+    event.preventDefault();
+    const form = event.target;
+    try {
+      await save(form);
+      $("editor").close("save");
+      await load();
+    } catch (e) {
+      form.querySelector(".error").textContent = e.message;
+    }
+  });
+
+  load();
+});
@@ -0,0 +1,24 @@
+// Package web is the web UI of rmm serve, a page browsing and editing the notes through the API
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the files of the web UI.
+// The page only loads its own scripts and can't be framed by other sites.
+func Handler() http.Handler {
+	files, _ := fs.Sub(static, "static")
+	fileServer := http.FileServerFS(files)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		fileServer.ServeHTTP(w, r)
+	})
+}